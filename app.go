@@ -1,13 +1,25 @@
 package kai
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
+	"sync"
+	"time"
 )
 
 type App struct {
 	Router *Router
+
+	// ShowRoutes prints the route table when Play starts the server.
+	ShowRoutes bool
+
+	mu     sync.Mutex
+	server *http.Server
+	health *Health
 }
 
 type Group struct {
@@ -31,45 +43,110 @@ func (a *App) Play(port int, message ...string) error {
 	} else {
 		fmt.Println("Server is running on port", port)
 	}
-	return http.ListenAndServe(":"+strconv.Itoa(port), a.Router)
+	if a.ShowRoutes {
+		a.PrintRoutes(os.Stdout)
+	}
+	server := &http.Server{Addr: ":" + strconv.Itoa(port), Handler: a.Router}
+	a.mu.Lock()
+	a.server = server
+	a.mu.Unlock()
+
+	err := server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
+
+// Shutdown stops the server started by Play gracefully: readiness starts
+// failing, the health ShutdownDelay passes so load balancers stop sending
+// traffic, then in-flight requests are drained until ctx is done.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.mu.Lock()
+	server, health := a.server, a.health
+	a.mu.Unlock()
+
+	if health != nil {
+		health.shuttingDown.Store(true)
+		if delay := health.opts.ShutdownDelay; delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+		}
+	}
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// ServeHTTP lets an App be used anywhere an http.Handler is expected.
+func (a *App) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	a.Router.ServeHTTP(w, req)
+}
+
 func (a *App) Group(prefix string) *Group {
 	return &Group{
 		Prefix: prefix,
 		app:    a,
 	}
 }
-func (a *App) GET(path string, handlers ...HandlerFunc) {
-	a.Router.GET(path, handlers...)
+func (a *App) GET(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.GET(path, handlers...)
 }
 
-func (a *App) POST(path string, handlers ...HandlerFunc) {
-	a.Router.POST(path, handlers...)
+func (a *App) POST(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.POST(path, handlers...)
 }
 
-func (a *App) PUT(path string, handlers ...HandlerFunc) {
-	a.Router.PUT(path, handlers...)
+func (a *App) PUT(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.PUT(path, handlers...)
 }
 
-func (a *App) DELETE(path string, handlers ...HandlerFunc) {
-	a.Router.DELETE(path, handlers...)
+func (a *App) DELETE(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.DELETE(path, handlers...)
 }
-func (g *Group) GET(path string, handlers ...HandlerFunc) {
+
+func (a *App) HEAD(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.HEAD(path, handlers...)
+}
+
+func (a *App) PATCH(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.PATCH(path, handlers...)
+}
+
+func (a *App) OPTIONS(path string, handlers ...HandlerFunc) *Route {
+	return a.Router.OPTIONS(path, handlers...)
+}
+func (g *Group) GET(path string, handlers ...HandlerFunc) *Route {
 	full := g.Prefix + path
-	g.app.GET(full, handlers...)
+	return g.app.GET(full, handlers...)
 }
-func (g *Group) POST(path string, handlers ...HandlerFunc) {
+func (g *Group) POST(path string, handlers ...HandlerFunc) *Route {
 	full := g.Prefix + path
-	g.app.POST(full, handlers...)
+	return g.app.POST(full, handlers...)
 }
 
-func (g *Group) PUT(path string, handlers ...HandlerFunc) {
+func (g *Group) PUT(path string, handlers ...HandlerFunc) *Route {
+	full := g.Prefix + path
+	return g.app.PUT(full, handlers...)
+}
+func (g *Group) DELETE(path string, handlers ...HandlerFunc) *Route {
+	full := g.Prefix + path
+	return g.app.DELETE(full, handlers...)
+}
+func (g *Group) HEAD(path string, handlers ...HandlerFunc) *Route {
+	full := g.Prefix + path
+	return g.app.HEAD(full, handlers...)
+}
+func (g *Group) PATCH(path string, handlers ...HandlerFunc) *Route {
 	full := g.Prefix + path
-	g.app.PUT(full, handlers...)
+	return g.app.PATCH(full, handlers...)
 }
-func (g *Group) DELETE(path string, handlers ...HandlerFunc) {
+func (g *Group) OPTIONS(path string, handlers ...HandlerFunc) *Route {
 	full := g.Prefix + path
-	g.app.DELETE(full, handlers...)
+	return g.app.OPTIONS(full, handlers...)
 }
 func (a *App) Use(middleware ...HandlerFunc) {
 	a.Router.Use(middleware...)
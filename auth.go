@@ -0,0 +1,206 @@
+package kai
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+// PrincipalKey is the Context.Keys entry holding the authenticated *Principal.
+const PrincipalKey = "kai.principal"
+
+// Principal is who a request was authenticated as.
+type Principal struct {
+	// ID is the username, key owner or token subject.
+	ID string `json:"id"`
+	// Scheme is the authentication used: "basic", "apikey", "jwt", ...
+	Scheme string   `json:"scheme"`
+	Roles  []string `json:"roles,omitempty"`
+	// Attributes carries anything else the app attaches to the principal.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// Principal returns the authenticated principal, or nil for anonymous requests.
+func (c *Context) Principal() *Principal {
+	value, _ := c.Get(PrincipalKey)
+	p, _ := value.(*Principal)
+	return p
+}
+
+// SetPrincipal records who the request is authenticated as, for custom
+// authentication middleware.
+func (c *Context) SetPrincipal(p *Principal) {
+	c.Set(PrincipalKey, p)
+}
+
+const authChallengeKey = "kai.authChallenge"
+
+// SetAuthChallenge records the WWW-Authenticate challenge Authorize sends
+// when it rejects an anonymous request, for authentication middleware that
+// lets anonymous requests through (such as JWT with Optional).
+func (c *Context) SetAuthChallenge(challenge string) {
+	c.Set(authChallengeKey, challenge)
+}
+
+func (c *Context) authChallenge() string {
+	value, _ := c.Get(authChallengeKey)
+	if challenge, ok := value.(string); ok && challenge != "" {
+		return challenge
+	}
+	return "Bearer"
+}
+
+// ---------------------------
+// Basic Auth
+// ---------------------------
+
+type BasicAuthOptions struct {
+	// Accounts maps usernames to passwords.
+	Accounts map[string]string
+	// Validator checks credentials instead of Accounts, e.g. against a
+	// database of password hashes. It should compare in constant time. To
+	// attach roles for Authorize, it may call c.SetPrincipal; that principal
+	// is kept instead of the default one.
+	Validator func(c *Context, username, password string) bool
+	// Realm is sent in the WWW-Authenticate challenge. Defaults to "Restricted".
+	Realm string
+}
+
+// BasicAuth protects routes with HTTP Basic authentication. The username
+// becomes the Principal ID unless the Validator set a principal itself.
+func BasicAuth(opts BasicAuthOptions) HandlerFunc {
+	if opts.Accounts == nil && opts.Validator == nil {
+		panic("Kai BasicAuth: Accounts or Validator is required")
+	}
+	if opts.Realm == "" {
+		opts.Realm = "Restricted"
+	}
+	validate := opts.Validator
+	if validate == nil {
+		validate = accountsValidator(opts.Accounts)
+	}
+	challenge := fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", opts.Realm)
+
+	return func(c *Context) {
+		before := c.Principal()
+		username, password, ok := c.Request.BasicAuth()
+		if !ok || !validate(c, username, password) {
+			c.SetHeader("WWW-Authenticate", challenge)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]any{
+				"error": "unauthorized",
+			})
+			return
+		}
+		if p := c.Principal(); p == nil || p == before {
+			c.SetPrincipal(&Principal{ID: username, Scheme: "basic"})
+		}
+		c.Next()
+	}
+}
+
+// accountsValidator compares SHA-256 digests of every account so neither the
+// username nor the password length leaks through timing.
+func accountsValidator(accounts map[string]string) func(*Context, string, string) bool {
+	type digest struct{ user, pass [sha256.Size]byte }
+	digests := make([]digest, 0, len(accounts))
+	for user, pass := range accounts {
+		digests = append(digests, digest{sha256.Sum256([]byte(user)), sha256.Sum256([]byte(pass))})
+	}
+	return func(_ *Context, username, password string) bool {
+		user := sha256.Sum256([]byte(username))
+		pass := sha256.Sum256([]byte(password))
+		match := 0
+		for _, d := range digests {
+			match |= subtle.ConstantTimeCompare(user[:], d.user[:]) & subtle.ConstantTimeCompare(pass[:], d.pass[:])
+		}
+		return match == 1
+	}
+}
+
+// ---------------------------
+// API Key Auth
+// ---------------------------
+
+type APIKeyOptions struct {
+	// Header, Query and Cookie name where the key is read from, tried in that
+	// order. With none set, the "X-API-Key" header is used.
+	Header string
+	Query  string
+	Cookie string
+	// Keys maps API keys to the Principal ID of their owner.
+	Keys map[string]string
+	// Lookup resolves a key instead of Keys, e.g. from a database. Return
+	// false for unknown or revoked keys.
+	Lookup func(c *Context, key string) (*Principal, bool)
+}
+
+// APIKeyAuth protects routes with a static API key.
+func APIKeyAuth(opts APIKeyOptions) HandlerFunc {
+	if opts.Keys == nil && opts.Lookup == nil {
+		panic("Kai APIKeyAuth: Keys or Lookup is required")
+	}
+	if opts.Header == "" && opts.Query == "" && opts.Cookie == "" {
+		opts.Header = "X-API-Key"
+	}
+	lookup := opts.Lookup
+	if lookup == nil {
+		lookup = keysLookup(opts.Keys)
+	}
+
+	return func(c *Context) {
+		key := ""
+		if opts.Header != "" {
+			key = c.Header(opts.Header)
+		}
+		if key == "" && opts.Query != "" {
+			key = c.Query(opts.Query)
+		}
+		if key == "" && opts.Cookie != "" {
+			key, _ = c.Cookie(opts.Cookie)
+		}
+		if key == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]any{
+				"error": "missing API key",
+			})
+			return
+		}
+
+		principal, ok := lookup(c, key)
+		if !ok || principal == nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, map[string]any{
+				"error": "invalid API key",
+			})
+			return
+		}
+		if principal.Scheme == "" {
+			principal.Scheme = "apikey"
+		}
+		c.SetPrincipal(principal)
+		c.Next()
+	}
+}
+
+func keysLookup(keys map[string]string) func(*Context, string) (*Principal, bool) {
+	type entry struct {
+		digest [sha256.Size]byte
+		owner  string
+	}
+	entries := make([]entry, 0, len(keys))
+	for key, owner := range keys {
+		entries = append(entries, entry{sha256.Sum256([]byte(key)), owner})
+	}
+	return func(_ *Context, key string) (*Principal, bool) {
+		digest := sha256.Sum256([]byte(key))
+		owner, found := "", false
+		for _, e := range entries {
+			if subtle.ConstantTimeCompare(digest[:], e.digest[:]) == 1 {
+				owner, found = e.owner, true
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		return &Principal{ID: owner}, true
+	}
+}
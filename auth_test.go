@@ -0,0 +1,106 @@
+package kai_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func whoami(c *kai.Context) {
+	c.JSON(http.StatusOK, c.Principal())
+}
+
+func TestBasicAuth(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/", kai.BasicAuth(kai.BasicAuthOptions{Accounts: map[string]string{"ops": "secret"}, Realm: "internal"}), whoami)
+	client := kaitest.New(t, app)
+
+	client.GET("/").BasicAuth("ops", "secret").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON(map[string]any{"id": "ops", "scheme": "basic"})
+	client.GET("/").BasicAuth("ops", "wrong").Do().
+		ExpectStatus(http.StatusUnauthorized).
+		ExpectHeader("WWW-Authenticate", `Basic realm="internal", charset="UTF-8"`)
+	client.GET("/").Do().ExpectStatus(http.StatusUnauthorized)
+}
+
+func TestBasicAuthValidatorPrincipalWithAuthorize(t *testing.T) {
+	policy := kai.NewPolicy().Grant("admin", "reports:*")
+	app := kai.NewApp()
+	auth := kai.BasicAuth(kai.BasicAuthOptions{
+		Validator: func(c *kai.Context, username, password string) bool {
+			if password != "pw" {
+				return false
+			}
+			roles := []string{"viewer"}
+			if username == "root" {
+				roles = []string{"admin"}
+			}
+			c.SetPrincipal(&kai.Principal{ID: username, Scheme: "basic", Roles: roles})
+			return true
+		},
+	})
+	app.GET("/reports", auth, kai.Authorize(policy, kai.RequirePermission("reports:read")), whoami)
+	app.GET("/admin", auth, kai.Authorize(nil, kai.RequireRole("admin")), whoami)
+	client := kaitest.New(t, app)
+
+	client.GET("/reports").BasicAuth("root", "pw").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSONPath("roles", []string{"admin"})
+	client.GET("/admin").BasicAuth("root", "pw").Do().ExpectStatus(http.StatusOK)
+	client.GET("/admin").BasicAuth("guest", "pw").Do().
+		ExpectStatus(http.StatusForbidden).
+		ExpectJSON(map[string]any{"error": "Forbidden"})
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/", kai.APIKeyAuth(kai.APIKeyOptions{Header: "X-API-Key", Query: "api_key", Keys: map[string]string{"k1": "billing"}}), whoami)
+	client := kaitest.New(t, app)
+
+	client.GET("/").Header("X-API-Key", "k1").Do().ExpectStatus(http.StatusOK).ExpectJSONPath("id", "billing")
+	client.GET("/").Query("api_key", "k1").Do().ExpectStatus(http.StatusOK)
+	client.GET("/").Header("X-API-Key", "nope").Do().ExpectStatus(http.StatusUnauthorized).
+		ExpectJSON(map[string]any{"error": "invalid API key"})
+	client.GET("/").Do().ExpectStatus(http.StatusUnauthorized).
+		ExpectJSON(map[string]any{"error": "missing API key"})
+}
+
+func TestAPIKeyAuthLookupAndCookie(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/", kai.APIKeyAuth(kai.APIKeyOptions{
+		Cookie: "api_key",
+		Lookup: func(c *kai.Context, key string) (*kai.Principal, bool) {
+			if key != "live-123" {
+				return nil, false
+			}
+			return &kai.Principal{ID: "svc-billing", Roles: []string{"service"}}, true
+		},
+	}), whoami)
+	client := kaitest.New(t, app)
+
+	client.GET("/").Cookie(&http.Cookie{Name: "api_key", Value: "live-123"}).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON(map[string]any{"id": "svc-billing", "scheme": "apikey", "roles": []string{"service"}})
+	client.GET("/").Cookie(&http.Cookie{Name: "api_key", Value: "revoked"}).Do().ExpectStatus(http.StatusUnauthorized)
+	// Only the configured source is read.
+	client.GET("/").Header("X-API-Key", "live-123").Do().ExpectStatus(http.StatusUnauthorized)
+}
+
+func TestAuthMiddlewareRequiresCredentials(t *testing.T) {
+	for name, build := range map[string]func(){
+		"basic":  func() { kai.BasicAuth(kai.BasicAuthOptions{}) },
+		"apikey": func() { kai.APIKeyAuth(kai.APIKeyOptions{Header: "X-Key"}) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic without credentials")
+				}
+			}()
+			build()
+		})
+	}
+}
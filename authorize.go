@@ -0,0 +1,165 @@
+package kai
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// Policy maps roles to permissions. Permissions are strings such as
+// "posts:write"; a grant of "posts:*" covers every "posts:" permission and
+// "*" covers everything. Build the policy at startup; it is read-only while
+// serving.
+type Policy struct {
+	grants  map[string][]string
+	parents map[string][]string
+}
+
+func NewPolicy() *Policy {
+	return &Policy{
+		grants:  make(map[string][]string),
+		parents: make(map[string][]string),
+	}
+}
+
+// Grant gives role the permissions.
+func (p *Policy) Grant(role string, permissions ...string) *Policy {
+	p.grants[role] = append(p.grants[role], permissions...)
+	return p
+}
+
+// Inherit makes role include every permission and role of parents, so an
+// "editor" inheriting "viewer" passes RequireRole("viewer").
+func (p *Policy) Inherit(role string, parents ...string) *Policy {
+	p.parents[role] = append(p.parents[role], parents...)
+	return p
+}
+
+// expand returns roles plus every role they inherit.
+func (p *Policy) expand(roles []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	var walk func(role string)
+	walk = func(role string) {
+		if seen[role] {
+			return
+		}
+		seen[role] = true
+		out = append(out, role)
+		if p != nil {
+			for _, parent := range p.parents[role] {
+				walk(parent)
+			}
+		}
+	}
+	for _, role := range roles {
+		walk(role)
+	}
+	return out
+}
+
+// HasRole reports whether roles include role directly or by inheritance.
+func (p *Policy) HasRole(roles []string, role string) bool {
+	return slices.Contains(p.expand(roles), role)
+}
+
+// Can reports whether roles grant permission.
+func (p *Policy) Can(roles []string, permission string) bool {
+	if p == nil {
+		return false
+	}
+	for _, role := range p.expand(roles) {
+		for _, grant := range p.grants[role] {
+			if permissionMatches(grant, permission) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func permissionMatches(grant, permission string) bool {
+	if grant == "*" || grant == permission {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(grant, "*")
+	return ok && strings.HasSuffix(prefix, ":") && strings.HasPrefix(permission, prefix)
+}
+
+// ---------------------------
+// Requirements
+// ---------------------------
+
+// Requirement is one condition Authorize checks against the principal.
+type Requirement struct {
+	desc  string
+	check func(c *Context, principal *Principal, policy *Policy) bool
+}
+
+// RequirePermission passes when the principal's roles grant every permission.
+func RequirePermission(permissions ...string) Requirement {
+	return Requirement{
+		desc: "permission " + strings.Join(permissions, ", "),
+		check: func(_ *Context, principal *Principal, policy *Policy) bool {
+			for _, permission := range permissions {
+				if !policy.Can(principal.Roles, permission) {
+					return false
+				}
+			}
+			return true
+		},
+	}
+}
+
+// RequireRole passes when the principal has any of roles, directly or by inheritance.
+func RequireRole(roles ...string) Requirement {
+	return Requirement{
+		desc: "role " + strings.Join(roles, " or "),
+		check: func(_ *Context, principal *Principal, policy *Policy) bool {
+			for _, role := range roles {
+				if policy.HasRole(principal.Roles, role) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// RequireFunc passes when fn returns true, for attribute-based rules such
+// as "only the author may edit a post". name appears in the recorded error.
+func RequireFunc(name string, fn func(c *Context, principal *Principal) bool) Requirement {
+	return Requirement{
+		desc: name,
+		check: func(c *Context, principal *Principal, _ *Policy) bool {
+			return fn(c, principal)
+		},
+	}
+}
+
+// Authorize lets a request through only when an authentication middleware
+// has set a principal and every requirement passes. Anonymous requests get
+// 401 with the challenge set by c.SetAuthChallenge ("Bearer" by default)
+// and denied ones 403, both through Context.AbortWithError so the router's
+// ErrorHandler shapes the response. policy may be nil when only
+// RequireRole and RequireFunc are used.
+func Authorize(policy *Policy, requirements ...Requirement) HandlerFunc {
+	return func(c *Context) {
+		principal := c.Principal()
+		if principal == nil {
+			c.AbortWithError(utils.ErrUnauthorized.WithHeader("WWW-Authenticate", c.authChallenge()))
+			return
+		}
+		for _, req := range requirements {
+			if !req.check(c, principal, policy) {
+				c.AbortWithError(utils.WrapHTTPError(http.StatusForbidden, "Forbidden",
+					fmt.Errorf("principal %q does not meet requirement: %s", principal.ID, req.desc)))
+				return
+			}
+		}
+		c.Next()
+	}
+}
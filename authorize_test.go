@@ -0,0 +1,68 @@
+package kai_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestPolicy(t *testing.T) {
+	policy := kai.NewPolicy().
+		Grant("viewer", "posts:read").
+		Grant("editor", "posts:*").
+		Inherit("editor", "viewer").
+		Grant("admin", "*")
+
+	cases := []struct {
+		roles []string
+		perm  string
+		want  bool
+	}{
+		{[]string{"viewer"}, "posts:read", true},
+		{[]string{"viewer"}, "posts:write", false},
+		{[]string{"editor"}, "posts:write", true},
+		{[]string{"editor"}, "users:read", false},
+		{[]string{"admin"}, "users:delete", true},
+		{nil, "posts:read", false},
+	}
+	for _, tc := range cases {
+		if got := policy.Can(tc.roles, tc.perm); got != tc.want {
+			t.Errorf("Can(%v, %q) = %v, want %v", tc.roles, tc.perm, got, tc.want)
+		}
+	}
+	if !policy.HasRole([]string{"editor"}, "viewer") {
+		t.Error("editor should inherit viewer")
+	}
+}
+
+func TestAuthorizeAnonymousGetsChallenge(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	app := kai.NewApp()
+	app.GET("/open", kai.Authorize(nil), whoami)
+	app.GET("/jwt", kai.JWT(kai.JWTOptions{Secret: secret, Optional: true, Realm: "orders"}), kai.Authorize(nil), whoami)
+	client := kaitest.New(t, app)
+
+	client.GET("/open").Do().
+		ExpectStatus(http.StatusUnauthorized).
+		ExpectHeader("WWW-Authenticate", "Bearer").
+		ExpectJSON(map[string]any{"error": "Unauthorized"})
+	client.GET("/jwt").Do().
+		ExpectStatus(http.StatusUnauthorized).
+		ExpectHeader("WWW-Authenticate", `Bearer realm="orders"`)
+}
+
+func TestAuthorizeRequireFunc(t *testing.T) {
+	app := kai.NewApp()
+	app.PUT("/posts/:author", func(c *kai.Context) {
+		c.SetPrincipal(&kai.Principal{ID: "ada"})
+		c.Next()
+	}, kai.Authorize(nil, kai.RequireFunc("author", func(c *kai.Context, p *kai.Principal) bool {
+		return c.Param("author") == p.ID
+	})), whoami)
+	client := kaitest.New(t, app)
+
+	client.PUT("/posts/ada").Do().ExpectStatus(http.StatusOK)
+	client.PUT("/posts/bob").Do().ExpectStatus(http.StatusForbidden)
+}
@@ -0,0 +1,361 @@
+package kai
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// ErrPreconditionFailed is the 412 sent when If-Match or If-Unmodified-Since
+// does not hold, typically because another client changed the resource.
+var ErrPreconditionFailed = utils.NewHTTPError(http.StatusPreconditionFailed, "precondition failed")
+
+// ---------------------------
+// Cache-Control
+// ---------------------------
+
+// CacheDirective is one Cache-Control directive.
+type CacheDirective string
+
+const (
+	CachePublic          CacheDirective = "public"
+	CachePrivate         CacheDirective = "private"
+	CacheNoCache         CacheDirective = "no-cache"
+	CacheNoStore         CacheDirective = "no-store"
+	CacheNoTransform     CacheDirective = "no-transform"
+	CacheMustRevalidate  CacheDirective = "must-revalidate"
+	CacheProxyRevalidate CacheDirective = "proxy-revalidate"
+	CacheImmutable       CacheDirective = "immutable"
+)
+
+// CacheMaxAge lets any cache reuse the response for d.
+func CacheMaxAge(d time.Duration) CacheDirective {
+	return cacheSeconds("max-age", d)
+}
+
+// CacheSMaxAge overrides max-age for shared caches such as CDNs.
+func CacheSMaxAge(d time.Duration) CacheDirective {
+	return cacheSeconds("s-maxage", d)
+}
+
+// CacheStaleWhileRevalidate lets caches serve a stale response for d while
+// they revalidate in the background.
+func CacheStaleWhileRevalidate(d time.Duration) CacheDirective {
+	return cacheSeconds("stale-while-revalidate", d)
+}
+
+// CacheStaleIfError lets caches serve a stale response for d when the origin fails.
+func CacheStaleIfError(d time.Duration) CacheDirective {
+	return cacheSeconds("stale-if-error", d)
+}
+
+func cacheSeconds(name string, d time.Duration) CacheDirective {
+	return CacheDirective(name + "=" + strconv.FormatInt(max(int64(d/time.Second), 0), 10))
+}
+
+// CacheControl sets the Cache-Control header, e.g.
+// c.CacheControl(kai.CachePublic, kai.CacheMaxAge(time.Hour)).
+func (c *Context) CacheControl(directives ...CacheDirective) {
+	parts := make([]string, len(directives))
+	for i, d := range directives {
+		parts[i] = string(d)
+	}
+	c.SetHeader("Cache-Control", strings.Join(parts, ", "))
+}
+
+// ---------------------------
+// Validators & Preconditions
+// ---------------------------
+
+// SetETag sets the ETag header. A bare tag is quoted; a quoted or W/ prefixed
+// tag is used as is.
+func (c *Context) SetETag(tag string) {
+	if !strings.HasPrefix(tag, `"`) && !strings.HasPrefix(tag, `W/"`) {
+		tag = strconv.Quote(tag)
+	}
+	c.SetHeader("ETag", tag)
+}
+
+// SetLastModified sets the Last-Modified header.
+func (c *Context) SetLastModified(t time.Time) {
+	c.SetHeader("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions evaluates the request's conditional headers against
+// the ETag and Last-Modified already set on the response. It answers 304
+// for an unchanged GET or HEAD, or 412 (through AbortWithError) when
+// If-Match or If-Unmodified-Since fails, and returns false in both cases:
+//
+//	c.SetETag(item.Version)
+//	if !c.CheckPreconditions() {
+//	    return
+//	}
+//	// apply the update
+func (c *Context) CheckPreconditions() bool {
+	h := c.Writer.Header()
+	switch checkPreconditions(c.Request, h) {
+	case http.StatusNotModified:
+		writeNotModified(c.Request, h)
+		c.Abort()
+		c.Status(http.StatusNotModified)
+		return false
+	case http.StatusPreconditionFailed:
+		c.AbortWithError(ErrPreconditionFailed)
+		return false
+	}
+	return true
+}
+
+// checkPreconditions applies the conditional headers in RFC 9110 order and
+// returns 304, 412 or 0 when the request should proceed.
+func checkPreconditions(r *http.Request, h http.Header) int {
+	etag := h.Get("ETag")
+	lastModified, _ := http.ParseTime(h.Get("Last-Modified"))
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if im := r.Header.Get("If-Match"); im != "" {
+		if _, ok := etagMatch(im, etag, false); !ok {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !lastModified.IsZero() {
+		if lastModified.Truncate(time.Second).After(ius) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if _, ok := etagMatch(inm, etag, true); ok {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && safe && !lastModified.IsZero() {
+		if !lastModified.Truncate(time.Second).After(ims) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// etagMatch reports whether current is in the header's list of tags and
+// returns the tag that matched. "*" matches any current representation.
+// If-Match uses strong comparison, so weak tags never match it;
+// If-None-Match uses weak comparison. A tag Compress gave to a coded
+// variant ("v1-gzip") matches the tag it was derived from.
+func etagMatch(header, current string, weak bool) (string, bool) {
+	if current == "" {
+		return "", false
+	}
+	if !weak && strings.HasPrefix(current, "W/") {
+		return "", false
+	}
+	opaque := strings.TrimPrefix(current, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return current, true
+		}
+		candidate := tag
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = candidate[2:]
+		}
+		if candidate == opaque {
+			return tag, true
+		}
+		if base, _, ok := etagCoding(candidate); ok && base == opaque {
+			return tag, true
+		}
+	}
+	return "", false
+}
+
+// writeNotModified drops the headers a 304 must not carry, as
+// http.ServeContent does. When the client's If-None-Match named a coded
+// variant, that is the representation the 304 stands for, so its tag is
+// echoed back.
+func writeNotModified(r *http.Request, h http.Header) {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if tag, ok := etagMatch(inm, h.Get("ETag"), true); ok {
+			h.Set("ETag", tag)
+		}
+	}
+	h.Del("Content-Type")
+	h.Del("Content-Length")
+	h.Del("Content-Encoding")
+	if h.Get("ETag") != "" {
+		h.Del("Last-Modified")
+	}
+}
+
+// ---------------------------
+// ETag Middleware
+// ---------------------------
+
+type ETagOptions struct {
+	// Weak marks generated tags as weak (W/"..."), for bodies that may differ
+	// in bytes but not in meaning.
+	Weak bool
+	// MaxSize is the largest body buffered for hashing; bigger or flushed
+	// responses stream through without an ETag. Defaults to 1 MiB.
+	MaxSize int
+}
+
+// ETag buffers successful GET and HEAD responses, tags them with a hash of
+// the body unless the handler set its own ETag, and answers If-None-Match
+// and If-Modified-Since with 304 so unchanged payloads are not re-sent. A
+// failed If-Match or If-Unmodified-Since gets 412 without the body.
+// Register it after Compress so the tag is computed on the original body;
+// Compress then adds the coding to it ("v1-gzip").
+func ETag(opts ...ETagOptions) HandlerFunc {
+	o := firstOr(opts)
+	if o.MaxSize <= 0 {
+		o.MaxSize = 1 << 20
+	}
+
+	return func(c *Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		ew := &etagWriter{ResponseWriter: c.Writer, request: c.Request, opts: &o}
+		c.Writer = ew
+		defer func() {
+			c.Writer = ew.ResponseWriter
+			ew.finish()
+		}()
+		c.Next()
+	}
+}
+
+// etagWriter holds a 200 response until the handler returns. Anything else,
+// or a body over MaxSize, switches it to passing writes straight through.
+type etagWriter struct {
+	http.ResponseWriter
+	request *http.Request
+	opts    *ETagOptions
+
+	status      int
+	buf         []byte
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(status int) {
+	if w.passthrough || w.status != 0 {
+		return
+	}
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	if status != http.StatusOK {
+		w.release()
+	}
+}
+
+func (w *etagWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) > w.opts.MaxSize {
+		if err := w.release(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// release stops buffering and writes out what is held so far, untagged.
+func (w *etagWriter) release() error {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *etagWriter) finish() {
+	if w.passthrough {
+		return
+	}
+	if w.status == 0 && len(w.buf) == 0 {
+		// Nothing was written; leave the response to the server.
+		return
+	}
+	w.passthrough = true
+
+	h := w.Header()
+	if h.Get("ETag") == "" {
+		sum := sha256.Sum256(w.buf)
+		tag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		if w.opts.Weak {
+			tag = "W/" + tag
+		}
+		h.Set("ETag", tag)
+	}
+	if len(w.buf) > 0 && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	switch checkPreconditions(w.request, h) {
+	case http.StatusNotModified:
+		writeNotModified(w.request, h)
+		w.ResponseWriter.WriteHeader(http.StatusNotModified)
+		return
+	case http.StatusPreconditionFailed:
+		// The held body is for a representation the client did not ask for.
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		h.Del("Content-Encoding")
+		w.ResponseWriter.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	if h.Get("Content-Encoding") == "" {
+		h.Set("Content-Length", strconv.Itoa(len(w.buf)))
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(w.buf)
+}
+
+// Flush gives up on tagging: a streamed response is sent as it is produced.
+func (w *etagWriter) Flush() {
+	if !w.passthrough {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		w.release()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack hands the connection over, e.g. for WebSockets.
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.passthrough = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *etagWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
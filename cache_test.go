@@ -0,0 +1,153 @@
+package kai_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+var cacheModified = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// newCacheApp serves a versioned post the way the README shows, optionally
+// behind Compress.
+func newCacheApp(compress bool) *kai.App {
+	app := kai.NewApp()
+	if compress {
+		app.Use(kai.Compress(kai.CompressOptions{MinSize: 1}))
+	}
+	app.Use(kai.ETag())
+
+	version := "v1"
+	app.GET("/post", func(c *kai.Context) {
+		c.SetETag(version)
+		c.SetLastModified(cacheModified)
+		c.JSON(http.StatusOK, map[string]any{"title": strings.Repeat("hello ", 20)})
+	})
+	app.PUT("/post", func(c *kai.Context) {
+		c.SetETag(version)
+		c.SetLastModified(cacheModified)
+		if !c.CheckPreconditions() {
+			return
+		}
+		version = "v2"
+		c.String(http.StatusOK, "updated")
+	})
+	app.GET("/hashed", func(c *kai.Context) {
+		c.String(http.StatusOK, "same body every time")
+	})
+	return app
+}
+
+func TestCacheConditionalRequests(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		name := "identity"
+		if compress {
+			name = "gzip"
+		}
+		t.Run(name, func(t *testing.T) {
+			client := kaitest.New(t, newCacheApp(compress))
+			if compress {
+				client.SetHeader("Accept-Encoding", "gzip")
+			}
+
+			first := client.GET("/post").Do().ExpectStatus(http.StatusOK)
+			etag := first.Header.Get("ETag")
+			want := `"v1"`
+			if compress {
+				want = `"v1-gzip"`
+				first.ExpectHeader("Content-Encoding", "gzip")
+			}
+			if etag != want {
+				t.Fatalf("ETag = %q, want %q", etag, want)
+			}
+
+			client.GET("/post").Header("If-None-Match", etag).Do().
+				ExpectStatus(http.StatusNotModified).
+				ExpectHeader("ETag", etag).
+				ExpectBody("")
+			client.GET("/post").Header("If-None-Match", `"other"`).Do().
+				ExpectStatus(http.StatusOK)
+
+			client.GET("/post").Header("If-Modified-Since", cacheModified.Format(http.TimeFormat)).Do().
+				ExpectStatus(http.StatusNotModified)
+			client.GET("/post").Header("If-Modified-Since", cacheModified.Add(-time.Hour).Format(http.TimeFormat)).Do().
+				ExpectStatus(http.StatusOK)
+
+			client.PUT("/post").Header("If-Match", `"stale"`).Header("Accept-Encoding", "identity").Do().
+				ExpectStatus(http.StatusPreconditionFailed).
+				ExpectJSON(map[string]any{"error": "precondition failed"})
+			client.PUT("/post").Header("If-Match", etag).Header("Accept-Encoding", "identity").Do().
+				ExpectStatus(http.StatusOK).
+				ExpectBody("updated")
+			// The same tag is stale once the update went through.
+			client.PUT("/post").Header("If-Match", etag).Do().
+				ExpectStatus(http.StatusPreconditionFailed)
+		})
+	}
+}
+
+func TestCachePreconditionsOnWrite(t *testing.T) {
+	client := kaitest.New(t, newCacheApp(false))
+
+	client.PUT("/post").Header("If-Match", `W/"v1"`).Do().
+		ExpectStatus(http.StatusPreconditionFailed)
+	client.PUT("/post").Header("If-None-Match", "*").Do().
+		ExpectStatus(http.StatusPreconditionFailed)
+	client.PUT("/post").Header("If-Unmodified-Since", cacheModified.Add(-time.Hour).Format(http.TimeFormat)).Do().
+		ExpectStatus(http.StatusPreconditionFailed)
+	client.PUT("/post").Header("If-Unmodified-Since", cacheModified.Format(http.TimeFormat)).Do().
+		ExpectStatus(http.StatusOK)
+}
+
+func TestETagHashesBody(t *testing.T) {
+	client := kaitest.New(t, newCacheApp(false))
+
+	first := client.GET("/hashed").Do().ExpectStatus(http.StatusOK)
+	etag := first.Header.Get("ETag")
+	if !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("ETag = %q, want a strong tag", etag)
+	}
+	client.GET("/hashed").Do().ExpectHeader("ETag", etag)
+	client.GET("/hashed").Header("If-None-Match", "W/"+etag).Do().
+		ExpectStatus(http.StatusNotModified)
+}
+
+func TestETagFailedIfMatch(t *testing.T) {
+	client := kaitest.New(t, newCacheApp(false))
+
+	etag := client.GET("/hashed").Do().Header.Get("ETag")
+	client.GET("/hashed").Header("If-Match", etag).Do().ExpectStatus(http.StatusOK)
+	res := client.GET("/hashed").Header("If-Match", `"nope"`).Do().
+		ExpectStatus(http.StatusPreconditionFailed).
+		ExpectBody("")
+	if ct := res.Header.Get("Content-Type"); ct != "" {
+		t.Errorf("412 Content-Type = %q", ct)
+	}
+}
+
+func TestETagWithoutCompressibleBody(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.Compress(), kai.ETag())
+	app.GET("/small", func(c *kai.Context) { c.String(http.StatusOK, "tiny") })
+	client := kaitest.New(t, app).SetHeader("Accept-Encoding", "gzip")
+
+	etag := client.GET("/small").Do().ExpectHeader("Content-Encoding", "").Header.Get("ETag")
+	// A response that is never compressed keeps its plain tag on 304 too.
+	client.GET("/small").Header("If-None-Match", etag).Do().
+		ExpectStatus(http.StatusNotModified).
+		ExpectHeader("ETag", etag)
+}
+
+func TestCacheControl(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/", func(c *kai.Context) {
+		c.CacheControl(kai.CachePublic, kai.CacheMaxAge(time.Hour), kai.CacheStaleWhileRevalidate(30*time.Second))
+		c.Status(http.StatusNoContent)
+	})
+	kaitest.New(t, app).GET("/").Do().
+		ExpectHeader("Cache-Control", "public, max-age=3600, stale-while-revalidate=30")
+}
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -69,33 +68,29 @@ func CRUD_SHOWCASE_ROUTES(app *kai.App) {
 	api := app.Group("/api")
 
 	api.GET("/posts", listShowcasePosts())
-	api.GET("/posts/:id", getShowcasePost())
+	api.GET("/posts/:id<int>", getShowcasePost())
 	api.POST("/posts", createShowcasePost())
-	api.PUT("/posts/:id", updateShowcasePost())
-	api.DELETE("/posts/:id", deleteShowcasePost())
+	api.PUT("/posts/:id<int>", updateShowcasePost())
+	api.DELETE("/posts/:id<int>", deleteShowcasePost())
 
-	api.POST("/posts/:id/file", uploadShowcaseFile())
-	api.GET("/posts/:id/file", downloadShowcaseFile())
-	api.DELETE("/posts/:id/file", deleteShowcaseFile())
+	api.POST("/posts/:id<int>/file", uploadShowcaseFile())
+	api.GET("/posts/:id<int>/file", downloadShowcaseFile()).Name("post.file")
+	api.DELETE("/posts/:id<int>/file", deleteShowcaseFile())
 }
 
 func listShowcasePosts() kai.HandlerFunc {
 	return func(c *kai.Context) {
-		limit := 50
-		if rawLimit := c.Query("limit"); rawLimit != "" {
-			parsed, err := strconv.Atoi(rawLimit)
-			if err != nil || parsed <= 0 {
-				c.JSON(http.StatusBadRequest, map[string]any{
-					"error": "limit must be a positive integer",
-				})
-				return
-			}
-			limit = parsed
+		limit, err := c.QueryInt("limit", 50)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, map[string]any{
+				"error": "limit must be a positive integer",
+			})
+			return
 		}
 
 		var publishedFilter *bool
-		if rawPublished := c.Query("published"); rawPublished != "" {
-			parsed, err := strconv.ParseBool(rawPublished)
+		if rawPublished, ok := c.GetQuery("published"); ok && rawPublished != "" {
+			parsed, err := c.QueryBool("published")
 			if err != nil {
 				c.JSON(http.StatusBadRequest, map[string]any{
 					"error": "published must be true or false",
@@ -228,17 +223,9 @@ func uploadShowcaseFile() kai.HandlerFunc {
 		}
 		_ = file.Close()
 
-		fileBytes, err := c.GetFileBytes("file")
-		if err != nil {
-			c.JSON(http.StatusBadRequest, map[string]any{
-				"error": err.Error(),
-			})
-			return
-		}
-
 		safeName := sanitizeFilename(header)
 		dest := filepath.Join(os.TempDir(), "kai-showcase-uploads", fmt.Sprintf("post-%d-%s", id, safeName))
-		if err := c.SaveToDest(dest, "file"); err != nil {
+		if err := c.SaveUploadedFile(header, dest); err != nil {
 			c.JSON(http.StatusInternalServerError, map[string]any{
 				"error": "failed to save file",
 			})
@@ -251,9 +238,9 @@ func uploadShowcaseFile() kai.HandlerFunc {
 
 		updated := crudShowcaseStore.setAttachment(id, &showcaseFile{
 			OriginalName: header.Filename,
-			Size:         len(fileBytes),
+			Size:         int(header.Size),
 			MIME:         header.Header.Get("Content-Type"),
-			DownloadURL:  fmt.Sprintf("/api/posts/%d/file", id),
+			DownloadURL:  c.URLFor("post.file", "id", id),
 			StoredPath:   dest,
 		})
 
@@ -286,8 +273,7 @@ func downloadShowcaseFile() kai.HandlerFunc {
 			return
 		}
 
-		c.SetHeader("Content-Disposition", fmt.Sprintf("attachment; filename=%q", post.Attachment.OriginalName))
-		c.ServeFile(post.Attachment.StoredPath)
+		c.Attachment(post.Attachment.StoredPath, post.Attachment.OriginalName)
 	}
 }
 
@@ -329,7 +315,7 @@ func deleteShowcaseFile() kai.HandlerFunc {
 }
 
 func parsePostID(c *kai.Context) (int, bool) {
-	id, err := strconv.Atoi(c.Param("id"))
+	id, err := c.ParamInt("id")
 	if err != nil || id <= 0 {
 		c.JSON(http.StatusBadRequest, map[string]any{
 			"error": "id must be a positive integer",
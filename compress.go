@@ -0,0 +1,466 @@
+package kai
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// DefaultCompressibleTypes are the content types Compress handles by
+// default. "type/*" matches a whole type and "+suffix" a structured syntax
+// suffix; anything else must match the media type exactly.
+var DefaultCompressibleTypes = []string{
+	"text/*",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"application/wasm",
+	"image/svg+xml",
+	"+json",
+	"+xml",
+}
+
+type CompressOptions struct {
+	// Level is the compression level, from 1 (fastest) to 9 (smallest).
+	// 0 uses the default level.
+	Level int
+	// MinSize is the smallest body worth compressing. Defaults to 1024 bytes.
+	MinSize int
+	// ContentTypes replaces DefaultCompressibleTypes.
+	ContentTypes []string
+	// Encodings lists the codings offered, most preferred first. Defaults to
+	// gzip then deflate; the client's q-values decide, ties go to this order.
+	Encodings []string
+}
+
+// Compress compresses responses with the best coding the client accepts.
+// Bodies smaller than MinSize, non-compressible content types, responses
+// that already have a Content-Encoding, partial content, 204/304 responses
+// and HEAD requests are passed through unchanged. Vary: Accept-Encoding is
+// always added since the representation depends on it.
+func Compress(opts ...CompressOptions) HandlerFunc {
+	o := firstOr(opts)
+	if o.Level == 0 {
+		o.Level = gzip.DefaultCompression
+	}
+	if o.Level < gzip.HuffmanOnly || o.Level > gzip.BestCompression {
+		panic("Kai Compress: invalid compression level " + strconv.Itoa(o.Level))
+	}
+	if o.MinSize <= 0 {
+		o.MinSize = 1024
+	}
+	if o.ContentTypes == nil {
+		o.ContentTypes = DefaultCompressibleTypes
+	}
+	if o.Encodings == nil {
+		o.Encodings = []string{"gzip", "deflate"}
+	}
+	for _, enc := range o.Encodings {
+		if enc != "gzip" && enc != "deflate" {
+			panic("Kai Compress: unsupported encoding " + enc)
+		}
+	}
+
+	level := o.Level
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() any {
+			w, _ := gzip.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+		// HTTP's deflate coding is zlib-wrapped (RFC 9110 8.4.1.2), not raw DEFLATE.
+		"deflate": {New: func() any {
+			w, _ := zlib.NewWriterLevel(io.Discard, level)
+			return w
+		}},
+	}
+
+	return func(c *Context) {
+		addVary(c.Writer.Header(), "Accept-Encoding")
+		if c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.Request.Header.Get("Accept-Encoding"), o.Encodings)
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.Writer,
+			opts:           &o,
+			encoding:       encoding,
+			pool:           pools[encoding],
+		}
+		c.Writer = cw
+		defer func() {
+			c.Writer = cw.ResponseWriter
+			cw.Close()
+		}()
+		c.Next()
+	}
+}
+
+// GZip compresses responses with gzip at level. It is Compress limited to gzip.
+func GZip(level int) HandlerFunc {
+	return Compress(CompressOptions{Level: level, Encodings: []string{"gzip"}})
+}
+
+func addVary(h http.Header, value string) {
+	for _, v := range h.Values("Vary") {
+		for _, token := range strings.Split(v, ",") {
+			token = strings.TrimSpace(token)
+			if token == "*" || strings.EqualFold(token, value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
+// negotiateEncoding picks the offered coding with the highest q-value in
+// the Accept-Encoding header, honoring "*" and q=0 exclusions.
+func negotiateEncoding(header string, offered []string) string {
+	if header == "" {
+		return ""
+	}
+	q := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		weight := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					weight = f
+				}
+			}
+		}
+		if token == "*" {
+			wildcard = weight
+		} else {
+			q[token] = weight
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, enc := range offered {
+		weight, ok := q[enc]
+		if !ok {
+			weight = max(wildcard, 0)
+		}
+		if weight > bestQ {
+			best, bestQ = enc, weight
+		}
+	}
+	return best
+}
+
+func compressibleType(contentType string, allowed []string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	for _, pattern := range allowed {
+		switch {
+		case strings.HasSuffix(pattern, "/*"):
+			if strings.HasPrefix(mediaType, pattern[:len(pattern)-1]) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "+"):
+			if strings.HasSuffix(mediaType, pattern) {
+				return true
+			}
+		case mediaType == pattern:
+			return true
+		}
+	}
+	return false
+}
+
+// compressWriter buffers the start of the body until it knows whether
+// compressing is worthwhile, then either streams through the encoder or
+// writes the body unchanged.
+type compressWriter struct {
+	http.ResponseWriter
+	opts     *CompressOptions
+	encoding string
+	pool     *sync.Pool
+
+	status  int
+	buf     []byte
+	decided bool
+	enc     encoder
+	closed  bool
+}
+
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	// Informational responses go out immediately and do not end the header phase.
+	if status >= 100 && status < 200 && status != http.StatusSwitchingProtocols {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	w.status = status
+	if !bodyAllowed(status) {
+		w.decide(false)
+	}
+}
+
+func (w *compressWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if w.decided {
+		if w.enc != nil {
+			return w.enc.Write(p)
+		}
+		return w.ResponseWriter.Write(p)
+	}
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.opts.MinSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// decide commits the headers. sizeOK says whether the body is known to be
+// large enough to compress.
+func (w *compressWriter) decide(sizeOK bool) error {
+	w.decided = true
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	h := w.Header()
+	if len(w.buf) > 0 && h.Get("Content-Type") == "" {
+		// Sniff now: once compressed, net/http would sniff the wrong bytes.
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	compress := sizeOK &&
+		bodyAllowed(w.status) &&
+		w.status != http.StatusPartialContent &&
+		h.Get("Content-Encoding") == "" &&
+		h.Get("Content-Range") == "" &&
+		compressibleType(h.Get("Content-Type"), w.opts.ContentTypes)
+
+	if compress {
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", codedETag(etag, w.encoding))
+		}
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.enc = w.pool.Get().(encoder)
+		w.enc.Reset(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if w.enc != nil {
+		_, err = w.enc.Write(buf)
+	} else {
+		_, err = w.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// codedETag gives the compressed variant its own strong validator by adding
+// the coding to the tag: "v1" becomes "v1-gzip". Conditional request checks
+// strip the suffix again with etagCoding, so the tag a client got with a
+// compressed response still matches the resource.
+func codedETag(etag, coding string) string {
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + coding + `"`
+}
+
+// etagCoding splits a tag produced by codedETag into the original tag and
+// the coding, reporting false for any other tag.
+func etagCoding(etag string) (string, string, bool) {
+	for _, coding := range []string{"gzip", "deflate"} {
+		if base, ok := strings.CutSuffix(etag, "-"+coding+`"`); ok {
+			return base + `"`, coding, true
+		}
+	}
+	return "", "", false
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
+// Flush sends what is buffered. A streamed response is compressed even if
+// it has not reached MinSize yet, since more is likely to follow.
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Close finishes the response: a body still under MinSize is written as is.
+func (w *compressWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	if !w.decided {
+		if w.status == 0 && len(w.buf) == 0 {
+			// Nothing was written; leave the response to the server.
+			return nil
+		}
+		w.decide(false)
+	}
+	if w.enc == nil {
+		return nil
+	}
+	err := w.enc.Close()
+	w.enc.Reset(io.Discard)
+	w.pool.Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// Hijack hands the connection over, e.g. for WebSockets, bypassing compression.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ---------------------------
+// Request Decompression
+// ---------------------------
+
+// ErrDecompressedTooLarge is returned by request body reads once a
+// compressed body expands beyond DecompressOptions.MaxSize.
+var ErrDecompressedTooLarge = utils.NewHTTPError(http.StatusRequestEntityTooLarge, "decompressed request body too large")
+
+type DecompressOptions struct {
+	// MaxSize caps the decompressed body, guarding against zip bombs.
+	// Defaults to 10 MiB.
+	MaxSize int64
+}
+
+// Decompress transparently decodes request bodies sent with
+// Content-Encoding gzip or deflate, so BodyBytes, GetJSON and form parsing
+// see the original bytes. Other codings get 415. Reading past MaxSize
+// fails with ErrDecompressedTooLarge, which c.AbortWithError renders as 413.
+//
+// Place BodyLimit before Decompress to cap the compressed size as well.
+func Decompress(opts ...DecompressOptions) HandlerFunc {
+	o := firstOr(opts)
+	if o.MaxSize <= 0 {
+		o.MaxSize = 10 << 20
+	}
+
+	return func(c *Context) {
+		coding := strings.ToLower(strings.TrimSpace(c.Request.Header.Get("Content-Encoding")))
+		if coding == "" || coding == "identity" || c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		var reader io.ReadCloser
+		var err error
+		switch coding {
+		case "gzip", "x-gzip":
+			reader, err = gzip.NewReader(c.Request.Body)
+		case "deflate":
+			reader, err = newDeflateReader(c.Request.Body)
+		default:
+			c.SetHeader("Accept-Encoding", "gzip, deflate")
+			c.AbortWithError(utils.NewHTTPError(http.StatusUnsupportedMediaType, "unsupported Content-Encoding "+coding))
+			return
+		}
+		if err != nil {
+			c.AbortWithError(utils.WrapHTTPError(http.StatusBadRequest, "malformed "+coding+" request body", err))
+			return
+		}
+
+		c.Request.Body = &decompressBody{r: reader, compressed: c.Request.Body, remaining: o.MaxSize}
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// newDeflateReader accepts both zlib-wrapped deflate, which HTTP specifies,
+// and the raw deflate streams some clients send instead.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+type decompressBody struct {
+	r          io.ReadCloser
+	compressed io.ReadCloser
+	remaining  int64
+}
+
+func (b *decompressBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Probe for one more byte to tell "exactly MaxSize" from "too large".
+		var probe [1]byte
+		if n, _ := b.r.Read(probe[:]); n > 0 {
+			return 0, ErrDecompressedTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *decompressBody) Close() error {
+	b.r.Close()
+	return b.compressed.Close()
+}
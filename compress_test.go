@@ -0,0 +1,227 @@
+package kai_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+var largeText = strings.Repeat("kai compresses repetitive text well. ", 100)
+
+func newCompressApp(opts ...kai.CompressOptions) *kai.App {
+	app := kai.NewApp()
+	app.Use(kai.Compress(opts...))
+	app.GET("/text", func(c *kai.Context) { c.String(http.StatusOK, largeText) })
+	app.HEAD("/text", func(c *kai.Context) { c.String(http.StatusOK, largeText) })
+	app.GET("/small", func(c *kai.Context) { c.String(http.StatusOK, "tiny") })
+	app.GET("/png", func(c *kai.Context) {
+		c.SetHeader("Content-Type", "image/png")
+		c.Status(http.StatusOK)
+		c.Write([]byte(largeText))
+	})
+	app.GET("/encoded", func(c *kai.Context) {
+		c.SetHeader("Content-Encoding", "br")
+		c.String(http.StatusOK, largeText)
+	})
+	app.GET("/empty", func(c *kai.Context) { c.Status(http.StatusNoContent) })
+	return app
+}
+
+func decodeBody(t *testing.T, res *kaitest.Response) string {
+	t.Helper()
+	var r io.Reader = bytes.NewReader(res.Body)
+	switch res.Header.Get("Content-Encoding") {
+	case "gzip":
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	case "deflate":
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestCompressNegotiation(t *testing.T) {
+	client := kaitest.New(t, newCompressApp())
+
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip, deflate", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip;q=0.5, deflate", "deflate"},
+		{"*", "gzip"},
+		{"*, gzip;q=0", "deflate"},
+		{"br", ""},
+		{"identity", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		res := client.GET("/text").Header("Accept-Encoding", tt.accept).Do().
+			ExpectStatus(http.StatusOK).
+			ExpectHeader("Vary", "Accept-Encoding")
+		if got := res.Header.Get("Content-Encoding"); got != tt.want {
+			t.Errorf("Accept-Encoding %q: Content-Encoding = %q, want %q", tt.accept, got, tt.want)
+			continue
+		}
+		if tt.want != "" && len(res.Body) >= len(largeText) {
+			t.Errorf("Accept-Encoding %q: body not smaller (%d bytes)", tt.accept, len(res.Body))
+		}
+		if body := decodeBody(t, res); body != largeText {
+			t.Errorf("Accept-Encoding %q: decoded body differs", tt.accept)
+		}
+	}
+}
+
+func TestCompressSkips(t *testing.T) {
+	client := kaitest.New(t, newCompressApp())
+	client.SetHeader("Accept-Encoding", "gzip")
+
+	for _, path := range []string{"/small", "/png", "/empty"} {
+		res := client.GET(path).Do().ExpectHeader("Vary", "Accept-Encoding")
+		if enc := res.Header.Get("Content-Encoding"); enc != "" {
+			t.Errorf("%s: Content-Encoding = %q", path, enc)
+		}
+	}
+	client.GET("/small").Do().ExpectBody("tiny")
+	client.GET("/encoded").Do().ExpectHeader("Content-Encoding", "br").ExpectBody(largeText)
+	res := client.HEAD("/text").Do()
+	if enc := res.Header.Get("Content-Encoding"); enc != "" {
+		t.Errorf("HEAD: Content-Encoding = %q", enc)
+	}
+}
+
+func TestCompressOptions(t *testing.T) {
+	client := kaitest.New(t, newCompressApp(kai.CompressOptions{
+		Level:        gzip.BestSpeed,
+		MinSize:      2,
+		ContentTypes: []string{"image/png"},
+		Encodings:    []string{"deflate"},
+	}))
+	client.SetHeader("Accept-Encoding", "gzip, deflate")
+
+	res := client.GET("/png").Do().ExpectHeader("Content-Encoding", "deflate")
+	if decodeBody(t, res) != largeText {
+		t.Error("decoded body differs")
+	}
+	client.GET("/text").Do().ExpectBody(largeText)
+
+	res = kaitest.New(t, newCompressApp(kai.CompressOptions{MinSize: 2})).GET("/small").Header("Accept-Encoding", "gzip").Do().
+		ExpectHeader("Content-Encoding", "gzip")
+	if decodeBody(t, res) != "tiny" {
+		t.Error("small body did not round-trip")
+	}
+}
+
+func TestGZip(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.GZip(gzip.BestCompression))
+	app.GET("/", func(c *kai.Context) { c.String(http.StatusOK, largeText) })
+	client := kaitest.New(t, app)
+
+	client.GET("/").Header("Accept-Encoding", "deflate").Do().ExpectBody(largeText)
+	res := client.GET("/").Header("Accept-Encoding", "deflate, gzip;q=0.1").Do().ExpectHeader("Content-Encoding", "gzip")
+	if decodeBody(t, res) != largeText {
+		t.Error("decoded body differs")
+	}
+}
+
+func TestCompressInvalidOptionsPanic(t *testing.T) {
+	for name, opts := range map[string]kai.CompressOptions{
+		"level":    {Level: 42},
+		"encoding": {Encodings: []string{"br"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			kai.Compress(opts)
+		})
+	}
+}
+
+func newDecompressApp(opts ...kai.DecompressOptions) *kai.App {
+	app := kai.NewApp()
+	app.Use(kai.Decompress(opts...))
+	app.POST("/echo", func(c *kai.Context) {
+		body, err := c.BodyBytes()
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.String(http.StatusOK, c.Header("Content-Encoding")+":"+string(body))
+	})
+	return app
+}
+
+func encodeBody(t *testing.T, coding, body string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "zlib":
+		w = zlib.NewWriter(&buf)
+	case "flate":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	}
+	io.WriteString(w, body)
+	w.Close()
+	return &buf
+}
+
+func TestDecompress(t *testing.T) {
+	client := kaitest.New(t, newDecompressApp())
+
+	for _, tt := range []struct{ header, coding string }{
+		{"gzip", "gzip"},
+		{"x-gzip", "gzip"},
+		{"deflate", "zlib"},
+		{"deflate", "flate"},
+	} {
+		client.POST("/echo").Header("Content-Encoding", tt.header).Body("text/plain", encodeBody(t, tt.coding, largeText)).Do().
+			ExpectStatus(http.StatusOK).
+			ExpectBody(":" + largeText)
+	}
+	client.POST("/echo").Body("text/plain", strings.NewReader("plain")).Do().ExpectBody(":plain")
+	client.POST("/echo").Header("Content-Encoding", "identity").Body("text/plain", strings.NewReader("plain")).Do().
+		ExpectBody("identity:plain")
+}
+
+func TestDecompressErrors(t *testing.T) {
+	client := kaitest.New(t, newDecompressApp(kai.DecompressOptions{MaxSize: int64(len(largeText))}))
+
+	client.POST("/echo").Header("Content-Encoding", "gzip").Body("text/plain", encodeBody(t, "gzip", largeText)).Do().
+		ExpectStatus(http.StatusOK)
+	client.POST("/echo").Header("Content-Encoding", "gzip").Body("text/plain", encodeBody(t, "gzip", largeText+"!")).Do().
+		ExpectStatus(http.StatusRequestEntityTooLarge).
+		ExpectJSON(map[string]string{"error": "decompressed request body too large"})
+	client.POST("/echo").Header("Content-Encoding", "br").Body("text/plain", strings.NewReader("x")).Do().
+		ExpectStatus(http.StatusUnsupportedMediaType).
+		ExpectHeader("Accept-Encoding", "gzip, deflate")
+	client.POST("/echo").Header("Content-Encoding", "gzip").Body("text/plain", strings.NewReader("not gzip")).Do().
+		ExpectStatus(http.StatusBadRequest).
+		ExpectJSON(map[string]string{"error": "malformed gzip request body"})
+}
@@ -40,11 +40,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 )
 
 /*
@@ -72,6 +77,7 @@ type Context struct {
     // Core
     Writer          http.ResponseWriter
     Request         *http.Request
+    router          *Router // router serving this request, nil in hand-built contexts
 
     // Routing
     Path            string
@@ -209,15 +215,24 @@ func (c *Context) AbortWithStatusJSON(code int, obj any) {
     c.JSON(code, obj)
 }
 
+// AbortWithError records err, aborts the chain and responds through the
+// router's ErrorHandler (DefaultErrorHandler unless replaced).
+func (c *Context) AbortWithError(err error) {
+    c.AddError(err)
+    c.Abort()
+    handler := DefaultErrorHandler
+    if c.router != nil && c.router.ErrorHandler != nil {
+        handler = c.router.ErrorHandler
+    }
+    handler(c, err)
+}
+
 func (c *Context) Param(key string) string {
     return c.Params[key]
 }
 
 func (c *Context) Query(key string) string {
-    if c.queryCache == nil {
-        c.queryCache = c.Request.URL.Query()
-    }
-    values := c.queryCache[key]
+    values := c.queryValues()[key]
     if len(values) > 0 {
         return values[0]
     }
@@ -264,7 +279,11 @@ func (c *Context) ensureMultipartForm() error {
     if c.Request.MultipartForm != nil {
         return nil
     }
-    return c.Request.ParseMultipartForm(32 << 20)
+    maxMemory := int64(defaultMultipartMemory)
+    if c.router != nil && c.router.MaxMultipartMemory > 0 {
+        maxMemory = c.router.MaxMultipartMemory
+    }
+    return c.Request.ParseMultipartForm(maxMemory)
 }
 
 func (c *Context) GetFile(filename string) (multipart.File, error) {
@@ -292,16 +311,19 @@ func (c *Context) GetFileBytes(filename string) ([]byte, error) {
     return data, nil
 }
 
+// SaveToDest writes the bytes from the last GetFileBytes call to dest, or
+// streams the named form file straight to disk when nothing was read yet.
 func (c *Context) SaveToDest(dest string, filename string) error {
     if len(c.lastFileBytes) == 0 {
         if len(filename) == 0 {
             return errors.New("no file data to save")
         }
-        data, err := c.GetFileBytes(filename)
+        file, err := c.GetFile(filename)
         if err != nil {
             return err
         }
-        c.lastFileBytes = data
+        defer file.Close()
+        return copyToFile(dest, file)
     }
     if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
         return err
@@ -324,8 +346,82 @@ func (c *Context) Get(key string) (any, bool) {
 	return value, exists
 }
 
-func (c *Context) ServeFile(filepath string) {
-	http.ServeFile(c.Writer, c.Request, filepath)
+// ServeFile serves a file from disk, or from fsys when one is given.
+func (c *Context) ServeFile(filepath string, fsys ...fs.FS) {
+	if len(fsys) > 0 && fsys[0] != nil {
+		c.FileFromFS(filepath, fsys[0])
+		return
+	}
+	c.serveHTTP(func(w http.ResponseWriter) {
+		http.ServeFile(w, c.Request, filepath)
+	})
+}
+
+// FileFromFS serves name from fsys (e.g. an embed.FS) with ETag, Last-Modified
+// and range support.
+func (c *Context) FileFromFS(name string, fsys fs.FS) {
+	name, ok := cleanFSPath(name)
+	if !ok {
+		c.JSON(http.StatusBadRequest, map[string]any{"error": "invalid file path"})
+		return
+	}
+	info, err := fs.Stat(fsys, name)
+	if err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, map[string]any{"error": "file not found"})
+		return
+	}
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		c.SetHeader("Content-Type", ctype)
+	}
+	if err := serveFSContent(c, fsys, name, info, nil); err != nil {
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to read file"})
+	}
+}
+
+// Attachment serves a file from disk as a download named downloadName.
+func (c *Context) Attachment(filepath string, downloadName string) {
+	c.SetHeader("Content-Disposition", contentDisposition("attachment", downloadName))
+	c.serveHTTP(func(w http.ResponseWriter) {
+		http.ServeFile(w, c.Request, filepath)
+	})
+}
+
+// AttachmentFromFS serves name from fsys as a download named downloadName.
+func (c *Context) AttachmentFromFS(name string, fsys fs.FS, downloadName string) {
+	c.SetHeader("Content-Disposition", contentDisposition("attachment", downloadName))
+	c.FileFromFS(name, fsys)
+}
+
+// contentDisposition builds a header value with an ASCII filename fallback and
+// an RFC 5987 filename* parameter for names that need it.
+func contentDisposition(dispType, filename string) string {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r >= 0x7f || r == '"' || r == '\\' || r == '%' {
+			return '_'
+		}
+		return r
+	}, filename)
+
+	value := dispType + `; filename="` + fallback + `"`
+	if fallback != filename {
+		value += "; filename*=UTF-8''" + rfc5987Escape(filename)
+	}
+	return value
+}
+
+func rfc5987Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') ||
+			strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
 }
 
 func (c *Context) Redirect(code int, location string) {
@@ -0,0 +1,320 @@
+package kai
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// ErrCookieInvalid is returned when a signed or encrypted cookie fails
+// verification, was issued for another name, or has expired.
+var ErrCookieInvalid = utils.NewHTTPError(http.StatusBadRequest, "invalid cookie")
+
+// CookieOptions are the attributes of a cookie set by SetCookie.
+type CookieOptions struct {
+	// Path defaults to "/".
+	Path   string
+	Domain string
+	// MaxAge in seconds; 0 leaves it unset (a session cookie unless Expires is set).
+	MaxAge      int
+	Expires     time.Time
+	Secure      bool
+	HttpOnly    bool
+	SameSite    http.SameSite
+	Partitioned bool
+}
+
+// ---------------------------
+// Plain Cookies
+// ---------------------------
+
+// Cookie returns the unescaped value of the named request cookie, or
+// http.ErrNoCookie.
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Request.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	value, err := url.QueryUnescape(cookie.Value)
+	if err != nil {
+		return cookie.Value, nil
+	}
+	return value, nil
+}
+
+// SetCookie adds a Set-Cookie header. The value is query-escaped so any
+// string round-trips through Cookie.
+func (c *Context) SetCookie(name, value string, opts ...CookieOptions) {
+	var o CookieOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	if o.Path == "" {
+		o.Path = "/"
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:        name,
+		Value:       url.QueryEscape(value),
+		Path:        o.Path,
+		Domain:      o.Domain,
+		MaxAge:      o.MaxAge,
+		Expires:     o.Expires,
+		Secure:      o.Secure,
+		HttpOnly:    o.HttpOnly,
+		SameSite:    o.SameSite,
+		Partitioned: o.Partitioned,
+	})
+}
+
+// DeleteCookie expires the named cookie. Path and Domain must match the
+// ones it was set with.
+func (c *Context) DeleteCookie(name string, opts ...CookieOptions) {
+	var o CookieOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.MaxAge = -1
+	o.Expires = time.Unix(0, 0)
+	c.SetCookie(name, "", o)
+}
+
+// ---------------------------
+// Signed & Encrypted Cookies
+// ---------------------------
+
+// CookieKeys holds the secrets for signed and encrypted cookies, newest
+// first. New cookies use the first key; older keys are still accepted so
+// keys can be rotated without logging everyone out.
+type CookieKeys struct {
+	// Signing keys for HMAC-SHA256, at least 32 bytes each.
+	Signing [][]byte
+	// Encryption keys for AES-GCM, 16, 24 or 32 bytes each.
+	Encryption [][]byte
+}
+
+// CookieCodec signs and encrypts cookie values. Values are bound to the
+// cookie name, so a value cannot be replayed under another cookie, and
+// carry their expiry so a stale cookie is rejected even if the browser
+// keeps sending it.
+type CookieCodec struct {
+	signing [][]byte
+	aeads   []cipher.AEAD
+}
+
+func NewCookieCodec(keys CookieKeys) (*CookieCodec, error) {
+	if len(keys.Signing) == 0 && len(keys.Encryption) == 0 {
+		return nil, errors.New("kai: no cookie keys given")
+	}
+	codec := &CookieCodec{}
+	for i, key := range keys.Signing {
+		if len(key) < 32 {
+			return nil, errors.New("kai: signing key " + strconv.Itoa(i) + " is shorter than 32 bytes")
+		}
+		codec.signing = append(codec.signing, key)
+	}
+	for i, key := range keys.Encryption {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.New("kai: encryption key " + strconv.Itoa(i) + ": " + err.Error())
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		codec.aeads = append(codec.aeads, aead)
+	}
+	return codec, nil
+}
+
+// Sign returns value with an expiry and an HMAC of both, as
+// "value.expiry.mac" in unpadded base64url. A zero expires never expires.
+func (cc *CookieCodec) Sign(name, value string, expires time.Time) (string, error) {
+	if len(cc.signing) == 0 {
+		return "", errors.New("kai: no signing keys configured")
+	}
+	payload := b64.EncodeToString([]byte(value)) + "." + strconv.FormatInt(unixOrZero(expires), 10)
+	return payload + "." + b64.EncodeToString(cookieMAC(cc.signing[0], name, payload)), nil
+}
+
+// Verify checks a value produced by Sign with any configured signing key.
+func (cc *CookieCodec) Verify(name, signed string) (string, error) {
+	i := strings.LastIndexByte(signed, '.')
+	if i < 0 {
+		return "", ErrCookieInvalid
+	}
+	payload := signed[:i]
+	mac, err := b64.DecodeString(signed[i+1:])
+	if err != nil {
+		return "", ErrCookieInvalid
+	}
+	valid := false
+	for _, key := range cc.signing {
+		if hmac.Equal(mac, cookieMAC(key, name, payload)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", ErrCookieInvalid
+	}
+
+	encoded, rawExpiry, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", ErrCookieInvalid
+	}
+	expiry, err := strconv.ParseInt(rawExpiry, 10, 64)
+	if err != nil || expired(expiry) {
+		return "", ErrCookieInvalid
+	}
+	value, err := b64.DecodeString(encoded)
+	if err != nil {
+		return "", ErrCookieInvalid
+	}
+	return string(value), nil
+}
+
+// Encrypt seals value and its expiry with AES-GCM using the cookie name as
+// additional data, and returns nonce+ciphertext in unpadded base64url.
+func (cc *CookieCodec) Encrypt(name, value string, expires time.Time) (string, error) {
+	if len(cc.aeads) == 0 {
+		return "", errors.New("kai: no encryption keys configured")
+	}
+	aead := cc.aeads[0]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+8+len(value)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	plain := binary.BigEndian.AppendUint64(nil, uint64(unixOrZero(expires)))
+	plain = append(plain, value...)
+	return b64.EncodeToString(aead.Seal(nonce, nonce, plain, []byte(name))), nil
+}
+
+// Decrypt opens a value produced by Encrypt with any configured encryption key.
+func (cc *CookieCodec) Decrypt(name, sealed string) (string, error) {
+	data, err := b64.DecodeString(sealed)
+	if err != nil {
+		return "", ErrCookieInvalid
+	}
+	for _, aead := range cc.aeads {
+		if len(data) < aead.NonceSize() {
+			continue
+		}
+		plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(name))
+		if err != nil || len(plain) < 8 {
+			continue
+		}
+		if expired(int64(binary.BigEndian.Uint64(plain))) {
+			return "", ErrCookieInvalid
+		}
+		return string(plain[8:]), nil
+	}
+	return "", ErrCookieInvalid
+}
+
+var b64 = base64.RawURLEncoding
+
+func cookieMAC(key []byte, name, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func expired(unix int64) bool {
+	return unix != 0 && time.Now().Unix() >= unix
+}
+
+// cookieExpiry is the moment a cookie set with o stops being valid, or zero.
+func cookieExpiry(o CookieOptions) time.Time {
+	if o.MaxAge > 0 {
+		return time.Now().Add(time.Duration(o.MaxAge) * time.Second)
+	}
+	return o.Expires
+}
+
+// SetCookieKeys enables the signed and encrypted cookie helpers. It panics
+// on invalid keys, since the app cannot run safely without them.
+func (a *App) SetCookieKeys(keys CookieKeys) {
+	codec, err := NewCookieCodec(keys)
+	if err != nil {
+		panic("Kai cookies: " + err.Error())
+	}
+	a.Router.cookies = codec
+}
+
+// CookieCodec returns the codec configured by SetCookieKeys, or nil.
+func (a *App) CookieCodec() *CookieCodec {
+	return a.Router.cookies
+}
+
+func (c *Context) cookieCodec() *CookieCodec {
+	if c.router == nil || c.router.cookies == nil {
+		panic("Kai cookies: no cookie keys configured; call App.SetCookieKeys")
+	}
+	return c.router.cookies
+}
+
+// SetSignedCookie sets a cookie whose value is readable by the client but
+// cannot be changed without detection.
+func (c *Context) SetSignedCookie(name, value string, opts ...CookieOptions) error {
+	o := firstOr(opts)
+	signed, err := c.cookieCodec().Sign(name, value, cookieExpiry(o))
+	if err != nil {
+		return err
+	}
+	c.SetCookie(name, signed, o)
+	return nil
+}
+
+// SignedCookie returns the verified value of a cookie set by SetSignedCookie.
+// It returns http.ErrNoCookie if absent and ErrCookieInvalid if tampered
+// with or expired.
+func (c *Context) SignedCookie(name string) (string, error) {
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return c.cookieCodec().Verify(name, raw)
+}
+
+// SetEncryptedCookie sets a cookie whose value is hidden from the client and
+// cannot be changed without detection.
+func (c *Context) SetEncryptedCookie(name, value string, opts ...CookieOptions) error {
+	o := firstOr(opts)
+	sealed, err := c.cookieCodec().Encrypt(name, value, cookieExpiry(o))
+	if err != nil {
+		return err
+	}
+	c.SetCookie(name, sealed, o)
+	return nil
+}
+
+// EncryptedCookie returns the decrypted value of a cookie set by
+// SetEncryptedCookie, with the same errors as SignedCookie.
+func (c *Context) EncryptedCookie(name string) (string, error) {
+	raw, err := c.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return c.cookieCodec().Decrypt(name, raw)
+}
@@ -0,0 +1,141 @@
+package kai_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+var (
+	signingKey    = bytes.Repeat([]byte("s"), 32)
+	oldSigningKey = bytes.Repeat([]byte("o"), 32)
+	encryptionKey = bytes.Repeat([]byte("e"), 32)
+)
+
+func newCookieApp(keys kai.CookieKeys) *kai.App {
+	app := kai.NewApp()
+	app.SetCookieKeys(keys)
+	app.POST("/login", func(c *kai.Context) {
+		c.SetCookie("theme", "dark mode; v=1")
+		if err := c.SetSignedCookie("user", "alice", kai.CookieOptions{HttpOnly: true}); err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		if err := c.SetEncryptedCookie("secret", "s3cr3t", kai.CookieOptions{MaxAge: 60}); err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	app.GET("/me", func(c *kai.Context) {
+		theme, _ := c.Cookie("theme")
+		user, err := c.SignedCookie("user")
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		secret, err := c.EncryptedCookie("secret")
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.JSON(http.StatusOK, map[string]string{"theme": theme, "user": user, "secret": secret})
+	})
+	app.POST("/logout", func(c *kai.Context) {
+		c.DeleteCookie("user")
+		c.Status(http.StatusNoContent)
+	})
+	return app
+}
+
+func TestCookiesRoundTrip(t *testing.T) {
+	client := kaitest.New(t, newCookieApp(kai.CookieKeys{Signing: [][]byte{signingKey}, Encryption: [][]byte{encryptionKey}}))
+
+	res := client.POST("/login").Do().ExpectStatus(http.StatusNoContent)
+	if user := res.Cookie("user"); user == nil || !user.HttpOnly || user.Path != "/" {
+		t.Errorf("user cookie = %+v", user)
+	}
+	if secret := res.Cookie("secret"); secret == nil || strings.Contains(secret.Value, "s3cr3t") || secret.MaxAge != 60 {
+		t.Errorf("secret cookie = %+v", secret)
+	}
+
+	client.GET("/me").Do().ExpectStatus(http.StatusOK).
+		ExpectJSON(map[string]string{"theme": "dark mode; v=1", "user": "alice", "secret": "s3cr3t"})
+
+	res = client.POST("/logout").Do()
+	if user := res.Cookie("user"); user == nil || user.MaxAge >= 0 {
+		t.Errorf("deleted cookie = %+v", user)
+	}
+	for _, cookie := range client.Jar().Cookies(res.Request.URL) {
+		if cookie.Name == "user" {
+			t.Errorf("user cookie still in the jar after logout: %+v", cookie)
+		}
+	}
+}
+
+func TestSignedCookieTampering(t *testing.T) {
+	app := newCookieApp(kai.CookieKeys{Signing: [][]byte{signingKey}, Encryption: [][]byte{encryptionKey}})
+	res := kaitest.New(t, app).POST("/login").Do()
+	user, secret := res.Cookie("user"), res.Cookie("secret")
+
+	forged := *user
+	forged.Value = strings.Replace(user.Value, "YWxpY2U", "Ym9i", 1) // "alice" -> "bob"
+	kaitest.New(t, app).GET("/me").Cookie(&forged).Cookie(secret).Do().
+		ExpectStatus(http.StatusBadRequest).
+		ExpectJSON(map[string]string{"error": "invalid cookie"})
+
+	// A value signed for one cookie name is rejected under another.
+	renamed := &http.Cookie{Name: "secret", Value: user.Value}
+	kaitest.New(t, app).GET("/me").Cookie(user).Cookie(renamed).Do().ExpectStatus(http.StatusBadRequest)
+}
+
+func TestCookieKeyRotation(t *testing.T) {
+	old := newCookieApp(kai.CookieKeys{Signing: [][]byte{oldSigningKey}, Encryption: [][]byte{encryptionKey}})
+	res := kaitest.New(t, old).POST("/login").Do()
+	user, secret := res.Cookie("user"), res.Cookie("secret")
+
+	rotated := newCookieApp(kai.CookieKeys{Signing: [][]byte{signingKey, oldSigningKey}, Encryption: [][]byte{encryptionKey}})
+	kaitest.New(t, rotated).GET("/me").Cookie(user).Cookie(secret).Do().ExpectJSONPath("user", "alice")
+
+	dropped := newCookieApp(kai.CookieKeys{Signing: [][]byte{signingKey}, Encryption: [][]byte{encryptionKey}})
+	kaitest.New(t, dropped).GET("/me").Cookie(user).Cookie(secret).Do().ExpectStatus(http.StatusBadRequest)
+}
+
+func TestCookieCodecExpiry(t *testing.T) {
+	codec, err := kai.NewCookieCodec(kai.CookieKeys{Signing: [][]byte{signingKey}, Encryption: [][]byte{encryptionKey}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute)
+
+	signed, _ := codec.Sign("user", "alice", past)
+	if _, err := codec.Verify("user", signed); !errors.Is(err, kai.ErrCookieInvalid) {
+		t.Errorf("Verify(expired) error = %v", err)
+	}
+	sealed, _ := codec.Encrypt("user", "alice", past)
+	if _, err := codec.Decrypt("user", sealed); !errors.Is(err, kai.ErrCookieInvalid) {
+		t.Errorf("Decrypt(expired) error = %v", err)
+	}
+	sealed, _ = codec.Encrypt("user", "alice", time.Time{})
+	if v, err := codec.Decrypt("user", sealed); err != nil || v != "alice" {
+		t.Errorf("Decrypt = %q, %v", v, err)
+	}
+}
+
+func TestCookieCodecRejectsBadKeys(t *testing.T) {
+	for name, keys := range map[string]kai.CookieKeys{
+		"none":           {},
+		"short signing":  {Signing: [][]byte{[]byte("short")}},
+		"bad encryption": {Encryption: [][]byte{[]byte("not-aes")}},
+	} {
+		if _, err := kai.NewCookieCodec(keys); err == nil {
+			t.Errorf("%s: NewCookieCodec accepted %v", name, keys)
+		}
+	}
+}
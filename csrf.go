@@ -0,0 +1,223 @@
+package kai
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// CSRFMode selects where the CSRF secret lives.
+type CSRFMode int
+
+const (
+	// CSRFDoubleSubmit keeps the secret in a cookie and expects it back in a
+	// header or form field. Needs no server state.
+	CSRFDoubleSubmit CSRFMode = iota
+	// CSRFSynchronizer keeps the secret in the session; requires the Sessions middleware.
+	CSRFSynchronizer
+)
+
+type CSRFOptions struct {
+	Mode CSRFMode
+	// CookieName is the double-submit cookie. Defaults to "kai_csrf".
+	CookieName string
+	// Cookie sets the cookie attributes; SameSite defaults to Lax. The cookie
+	// is readable by scripts so they can copy it into the header.
+	Cookie CookieOptions
+	// Header and FormField carry the token on unsafe requests. Default to
+	// "X-CSRF-Token" and "csrf_token".
+	Header    string
+	FormField string
+	// TrustedOrigins are extra origins, like "https://admin.example.com",
+	// allowed to send unsafe requests besides the request's own host.
+	TrustedOrigins []string
+	// ExemptRoutes skips the check for these route patterns (as registered,
+	// e.g. "/webhooks/:provider") or exact paths.
+	ExemptRoutes []string
+	// Skip, when it returns true, skips the check for the request.
+	Skip func(c *Context) bool
+}
+
+// ErrCSRF is the error passed to AbortWithError when a request fails the CSRF check.
+var ErrCSRF = utils.NewHTTPError(http.StatusForbidden, "CSRF token missing or invalid")
+
+const (
+	csrfKey        = "kai.csrf"
+	csrfSessionKey = "_csrf"
+	csrfTokenSize  = 32
+)
+
+// csrfState is the per-request secret, created on first use.
+type csrfState struct {
+	opts   *CSRFOptions
+	ctx    *Context
+	secret []byte
+}
+
+// CSRF rejects POST, PUT, PATCH and DELETE requests (any method but GET,
+// HEAD, OPTIONS and TRACE) whose Origin or Referer is foreign or that do
+// not carry the token from c.CSRFToken. Failures go through
+// Context.AbortWithError with a 403.
+func CSRF(opts ...CSRFOptions) HandlerFunc {
+	o := firstOr(opts)
+	if o.CookieName == "" {
+		o.CookieName = "kai_csrf"
+	}
+	if o.Cookie.SameSite == 0 {
+		o.Cookie.SameSite = http.SameSiteLaxMode
+	}
+	o.Cookie.HttpOnly = false
+	if o.Header == "" {
+		o.Header = "X-CSRF-Token"
+	}
+	if o.FormField == "" {
+		o.FormField = "csrf_token"
+	}
+	o.TrustedOrigins = slices.Clone(o.TrustedOrigins)
+	for i, origin := range o.TrustedOrigins {
+		o.TrustedOrigins[i] = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	}
+
+	return func(c *Context) {
+		state := &csrfState{opts: &o, ctx: c}
+		state.load()
+		c.Set(csrfKey, state)
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+			c.Next()
+			return
+		}
+		if slices.Contains(o.ExemptRoutes, c.Route) || slices.Contains(o.ExemptRoutes, c.Request.URL.Path) ||
+			(o.Skip != nil && o.Skip(c)) {
+			c.Next()
+			return
+		}
+
+		if err := checkCSRFOrigin(c, o.TrustedOrigins); err != nil {
+			c.AbortWithError(utils.WrapHTTPError(ErrCSRF.Code, ErrCSRF.Message, err))
+			return
+		}
+		token := c.Header(o.Header)
+		if token == "" {
+			token = c.Request.PostFormValue(o.FormField)
+		}
+		if state.secret == nil || !validCSRFToken(token, state.secret) {
+			c.AbortWithError(utils.WrapHTTPError(ErrCSRF.Code, ErrCSRF.Message, errors.New("token mismatch")))
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *csrfState) load() {
+	var encoded string
+	if s.opts.Mode == CSRFSynchronizer {
+		encoded, _ = s.ctx.Session().Get(csrfSessionKey).(string)
+	} else {
+		encoded, _ = s.ctx.Cookie(s.opts.CookieName)
+	}
+	if secret, err := b64.DecodeString(encoded); err == nil && len(secret) == csrfTokenSize {
+		s.secret = secret
+	}
+}
+
+// ensure creates and stores a secret if the request did not bring one.
+func (s *csrfState) ensure() []byte {
+	if s.secret != nil {
+		return s.secret
+	}
+	s.secret = make([]byte, csrfTokenSize)
+	rand.Read(s.secret)
+	encoded := b64.EncodeToString(s.secret)
+	if s.opts.Mode == CSRFSynchronizer {
+		s.ctx.Session().Set(csrfSessionKey, encoded)
+	} else {
+		s.ctx.SetCookie(s.opts.CookieName, encoded, s.opts.Cookie)
+	}
+	return s.secret
+}
+
+// checkCSRFOrigin compares Origin, or Referer on HTTPS when Origin is
+// absent, with the request host and the trusted origins.
+func checkCSRFOrigin(c *Context, trusted []string) error {
+	source := c.Request.Header.Get("Origin")
+	if source == "" {
+		if c.Request.TLS == nil {
+			return nil
+		}
+		source = c.Request.Header.Get("Referer")
+		if source == "" {
+			return errors.New("referer missing")
+		}
+	}
+	u, err := url.Parse(source)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("origin %q is not allowed", source)
+	}
+	if strings.EqualFold(u.Host, c.Request.Host) ||
+		slices.Contains(trusted, strings.ToLower(u.Scheme+"://"+u.Host)) {
+		return nil
+	}
+	return fmt.Errorf("origin %q is not allowed", source)
+}
+
+// maskCSRFToken XORs the secret with a fresh one-time pad so the token in
+// each response differs, defeating BREACH-style compression attacks.
+func maskCSRFToken(secret []byte) string {
+	out := make([]byte, 2*csrfTokenSize)
+	rand.Read(out[:csrfTokenSize])
+	for i := range csrfTokenSize {
+		out[csrfTokenSize+i] = out[i] ^ secret[i]
+	}
+	return b64.EncodeToString(out)
+}
+
+// validCSRFToken accepts a masked token or the raw secret from the cookie.
+func validCSRFToken(token string, secret []byte) bool {
+	data, err := b64.DecodeString(token)
+	if err != nil {
+		return false
+	}
+	switch len(data) {
+	case csrfTokenSize:
+		return subtle.ConstantTimeCompare(data, secret) == 1
+	case 2 * csrfTokenSize:
+		unmasked := make([]byte, csrfTokenSize)
+		for i := range csrfTokenSize {
+			unmasked[i] = data[i] ^ data[csrfTokenSize+i]
+		}
+		return subtle.ConstantTimeCompare(unmasked, secret) == 1
+	}
+	return false
+}
+
+func (c *Context) csrfState() *csrfState {
+	value, _ := c.Get(csrfKey)
+	state, ok := value.(*csrfState)
+	if !ok {
+		panic("Kai CSRF: CSRF middleware is not installed")
+	}
+	return state
+}
+
+// CSRFToken returns a token to embed in forms or send in the CSRF header.
+// It creates the secret on first use, so call it before writing the body.
+func (c *Context) CSRFToken() string {
+	return maskCSRFToken(c.csrfState().ensure())
+}
+
+// CSRFField returns a hidden form input holding a fresh token, for templates.
+func (c *Context) CSRFField() template.HTML {
+	state := c.csrfState()
+	return template.HTML(fmt.Sprintf(`<input type="hidden" name="%s" value="%s">`,
+		template.HTMLEscapeString(state.opts.FormField), maskCSRFToken(state.ensure())))
+}
@@ -0,0 +1,74 @@
+package kai_test
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func newCSRFApp(opts kai.CSRFOptions) *kai.App {
+	app := kai.NewApp()
+	app.Use(kai.CSRF(opts))
+	app.GET("/form", func(c *kai.Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	app.POST("/form", func(c *kai.Context) { c.String(http.StatusOK, "saved") })
+	app.POST("/webhooks/:provider", func(c *kai.Context) { c.String(http.StatusOK, "hook") })
+	return app
+}
+
+func TestCSRFDoubleSubmit(t *testing.T) {
+	client := kaitest.New(t, newCSRFApp(kai.CSRFOptions{ExemptRoutes: []string{"/webhooks/:provider"}}))
+
+	token := client.GET("/form").Do().ExpectStatus(http.StatusOK).String()
+	second := client.GET("/form").Do().String()
+	if token == second {
+		t.Error("tokens should be masked differently on each request")
+	}
+
+	client.POST("/form").Header("X-CSRF-Token", token).Do().ExpectStatus(http.StatusOK).ExpectBody("saved")
+	client.POST("/form").Header("X-CSRF-Token", second).Do().ExpectStatus(http.StatusOK)
+	client.POST("/form").Form(url.Values{"csrf_token": {token}}).Do().ExpectStatus(http.StatusOK)
+	client.POST("/form").Do().
+		ExpectStatus(http.StatusForbidden).
+		ExpectJSON(map[string]any{"error": "CSRF token missing or invalid"})
+	client.POST("/form").Header("X-CSRF-Token", "bogus").Do().ExpectStatus(http.StatusForbidden)
+	client.POST("/webhooks/github").Do().ExpectStatus(http.StatusOK)
+
+	// A token is useless without the cookie it was derived from.
+	kaitest.New(t, newCSRFApp(kai.CSRFOptions{})).POST("/form").Header("X-CSRF-Token", token).Do().
+		ExpectStatus(http.StatusForbidden)
+}
+
+func TestCSRFOrigin(t *testing.T) {
+	client := kaitest.New(t, newCSRFApp(kai.CSRFOptions{TrustedOrigins: []string{"https://Admin.example.org/"}}))
+	token := client.GET("/form").Do().String()
+
+	client.POST("/form").Header("X-CSRF-Token", token).Header("Origin", "http://example.com").Do().
+		ExpectStatus(http.StatusOK)
+	client.POST("/form").Header("X-CSRF-Token", token).Header("Origin", "https://admin.example.org").Do().
+		ExpectStatus(http.StatusOK)
+	client.POST("/form").Header("X-CSRF-Token", token).Header("Origin", "https://evil.example").Do().
+		ExpectStatus(http.StatusForbidden)
+}
+
+func TestCSRFDoesNotModifyOptions(t *testing.T) {
+	trusted := []string{"https://Admin.example.org/"}
+	kai.CSRF(kai.CSRFOptions{TrustedOrigins: trusted})
+	if trusted[0] != "https://Admin.example.org/" {
+		t.Fatalf("TrustedOrigins was modified to %q", trusted[0])
+	}
+}
+
+func TestCSRFSynchronizer(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.Sessions(kai.NewMemorySessionStore()), kai.CSRF(kai.CSRFOptions{Mode: kai.CSRFSynchronizer}))
+	app.GET("/form", func(c *kai.Context) { c.String(http.StatusOK, c.CSRFToken()) })
+	app.POST("/form", func(c *kai.Context) { c.String(http.StatusOK, "saved") })
+	client := kaitest.New(t, app)
+
+	token := client.GET("/form").Do().String()
+	client.POST("/form").Header("X-CSRF-Token", token).Do().ExpectStatus(http.StatusOK)
+	client.POST("/form").Do().ExpectStatus(http.StatusForbidden)
+}
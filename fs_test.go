@@ -0,0 +1,98 @@
+package kai_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+// embedLike has no modification times, like an embed.FS.
+func embedLike() fstest.MapFS {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("body{}"))
+	w.Close()
+	return fstest.MapFS{
+		"site.css":         {Data: []byte("body{}")},
+		"site.css.gz":      {Data: gz.Bytes()},
+		"docs/report.pdf":  {Data: []byte("%PDF-1.7")},
+		"views/index.html": {Data: []byte(`<p>{{.}}</p>`)},
+	}
+}
+
+func TestStaticFSWithoutModTimes(t *testing.T) {
+	app := kai.NewApp()
+	app.StaticFS("/assets", embedLike(), kai.StaticOptions{Compress: true})
+	client := kaitest.New(t, app)
+
+	res := client.GET("/assets/site.css").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "text/css; charset=utf-8").
+		ExpectBody("body{}")
+	etag := res.Header.Get("ETag")
+	if etag == "" || res.Header.Get("Last-Modified") != "" {
+		t.Fatalf("ETag = %q, Last-Modified = %q", etag, res.Header.Get("Last-Modified"))
+	}
+	client.GET("/assets/site.css").Header("If-None-Match", etag).Do().ExpectStatus(http.StatusNotModified)
+
+	gz := client.GET("/assets/site.css").Header("Accept-Encoding", "gzip").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Encoding", "gzip").
+		ExpectHeader("Vary", "Accept-Encoding")
+	r, err := gzip.NewReader(bytes.NewReader(gz.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(r); string(body) != "body{}" {
+		t.Fatalf("decompressed body = %q", body)
+	}
+
+	client.GET("/assets/../site.css").Do().ExpectStatus(http.StatusBadRequest)
+	client.GET("/assets/missing.css").Do().ExpectStatus(http.StatusNotFound)
+}
+
+func TestFileFromFS(t *testing.T) {
+	files := embedLike()
+	app := kai.NewApp()
+	app.GET("/file/*name", func(c *kai.Context) { c.FileFromFS(c.Param("name"), files) })
+	app.GET("/download", func(c *kai.Context) { c.AttachmentFromFS("docs/report.pdf", files, c.Query("name")) })
+	client := kaitest.New(t, app)
+
+	client.GET("/file/docs/report.pdf").Do().ExpectStatus(http.StatusOK).ExpectBody("%PDF-1.7")
+	client.GET("/file/docs").Do().ExpectStatus(http.StatusNotFound)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Q1 report.pdf", `attachment; filename="Q1 report.pdf"`},
+		// Non-ASCII names get an ASCII fallback plus the RFC 5987 form.
+		{"résumé.pdf", `attachment; filename="r_sum_.pdf"; filename*=UTF-8''r%C3%A9sum%C3%A9.pdf`},
+		// Quotes and backslashes must not break out of the quoted string.
+		{`say "hi"\x.pdf`, `attachment; filename="say _hi__x.pdf"; filename*=UTF-8''say%20%22hi%22%5Cx.pdf`},
+	}
+	for _, tt := range tests {
+		client.GET("/download").Query("name", tt.name).Do().
+			ExpectStatus(http.StatusOK).
+			ExpectHeader("Content-Disposition", tt.want)
+	}
+}
+
+func TestLoadHTMLFS(t *testing.T) {
+	app := kai.NewApp()
+	if err := app.LoadHTMLFS(embedLike(), "views/*.html"); err != nil {
+		t.Fatal(err)
+	}
+	app.GET("/", func(c *kai.Context) { c.HTML(http.StatusOK, "views/index.html", "<hi>") })
+	res := kaitest.New(t, app).GET("/").Do().ExpectStatus(http.StatusOK)
+	if !strings.Contains(res.String(), "<p>&lt;hi&gt;</p>") {
+		t.Fatalf("body = %q", res.String())
+	}
+}
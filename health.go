@@ -0,0 +1,213 @@
+package kai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HealthCheck reports whether a dependency is usable. It should respect ctx,
+// which carries the check's timeout.
+type HealthCheck func(ctx context.Context) error
+
+type HealthOptions struct {
+	// LivePath and ReadyPath default to "/livez" and "/readyz".
+	LivePath  string
+	ReadyPath string
+	// Timeout bounds each check unless the check sets its own. Defaults to 2 seconds.
+	Timeout time.Duration
+	// CacheTTL reuses a check's last result for this long, so frequent probes
+	// do not hammer dependencies. Defaults to 1 second; negative disables caching.
+	CacheTTL time.Duration
+	// ShutdownDelay is how long App.Shutdown keeps serving with readiness
+	// failing before it stops accepting connections.
+	ShutdownDelay time.Duration
+}
+
+// Health serves liveness and readiness endpoints from named checks.
+type Health struct {
+	opts         HealthOptions
+	mu           sync.RWMutex
+	live         []*healthCheck
+	ready        []*healthCheck
+	shuttingDown atomic.Bool
+}
+
+type healthCheck struct {
+	name    string
+	fn      HealthCheck
+	timeout time.Duration
+
+	mu      sync.Mutex // held while running, so concurrent probes share one run
+	err     error
+	elapsed time.Duration
+	checked time.Time
+}
+
+// HealthResult is the outcome of one check in the JSON response.
+type HealthResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Health registers GET and HEAD routes for liveness and readiness and
+// returns the Health to add checks to. Both endpoints answer 200 when every
+// check passes and 503 otherwise; readiness also fails once App.Shutdown
+// has begun.
+func (a *App) Health(opts ...HealthOptions) *Health {
+	o := firstOr(opts)
+	if o.LivePath == "" {
+		o.LivePath = "/livez"
+	}
+	if o.ReadyPath == "" {
+		o.ReadyPath = "/readyz"
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = 2 * time.Second
+	}
+	if o.CacheTTL == 0 {
+		o.CacheTTL = time.Second
+	}
+
+	h := &Health{opts: o}
+	a.mu.Lock()
+	a.health = h
+	a.mu.Unlock()
+
+	live := func(c *Context) { h.serve(c, false) }
+	ready := func(c *Context) { h.serve(c, true) }
+	a.GET(o.LivePath, live)
+	a.HEAD(o.LivePath, live)
+	a.GET(o.ReadyPath, ready)
+	a.HEAD(o.ReadyPath, ready)
+	return h
+}
+
+// AddLivenessCheck adds a check to /livez. Keep these to conditions only a
+// restart fixes, such as a deadlocked worker; a failing database belongs in
+// readiness.
+func (h *Health) AddLivenessCheck(name string, check HealthCheck, timeout ...time.Duration) *Health {
+	h.mu.Lock()
+	h.live = append(h.live, h.newCheck(name, check, timeout))
+	h.mu.Unlock()
+	return h
+}
+
+// AddReadinessCheck adds a check to /readyz.
+func (h *Health) AddReadinessCheck(name string, check HealthCheck, timeout ...time.Duration) *Health {
+	h.mu.Lock()
+	h.ready = append(h.ready, h.newCheck(name, check, timeout))
+	h.mu.Unlock()
+	return h
+}
+
+func (h *Health) newCheck(name string, fn HealthCheck, timeout []time.Duration) *healthCheck {
+	check := &healthCheck{name: name, fn: fn, timeout: h.opts.Timeout}
+	if len(timeout) > 0 && timeout[0] > 0 {
+		check.timeout = timeout[0]
+	}
+	return check
+}
+
+// ShuttingDown reports whether App.Shutdown has started.
+func (h *Health) ShuttingDown() bool {
+	return h.shuttingDown.Load()
+}
+
+func (h *Health) serve(c *Context, readiness bool) {
+	h.mu.RLock()
+	checks := h.live
+	if readiness {
+		checks = h.ready
+	}
+	checks = append([]*healthCheck(nil), checks...)
+	h.mu.RUnlock()
+
+	results := make(map[string]HealthResult, len(checks))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := check.run(h.opts.CacheTTL)
+			mu.Lock()
+			results[check.name] = result
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	healthy := true
+	for _, result := range results {
+		if result.Status != "ok" {
+			healthy = false
+		}
+	}
+	body := map[string]any{"checks": results}
+	if readiness && h.ShuttingDown() {
+		healthy = false
+		body["shutting_down"] = true
+	}
+
+	code := http.StatusOK
+	body["status"] = "ok"
+	if !healthy {
+		code = http.StatusServiceUnavailable
+		body["status"] = "fail"
+	}
+	c.SetHeader("Cache-Control", "no-store")
+	if c.Request.Method == http.MethodHead {
+		c.Status(code)
+		return
+	}
+	c.JSON(code, body)
+}
+
+func (hc *healthCheck) run(ttl time.Duration) HealthResult {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	if hc.checked.IsZero() || ttl < 0 || time.Since(hc.checked) >= ttl {
+		start := time.Now()
+		hc.err = hc.call()
+		hc.elapsed = time.Since(start)
+		hc.checked = time.Now()
+	}
+
+	result := HealthResult{Status: "ok", DurationMS: hc.elapsed.Milliseconds()}
+	if hc.err != nil {
+		result.Status = "fail"
+		result.Error = hc.err.Error()
+	}
+	return result
+}
+
+// call runs the check in its own goroutine so one that ignores its context
+// still cannot hold the probe past the timeout.
+func (hc *healthCheck) call() error {
+	ctx, cancel := context.WithTimeout(context.Background(), hc.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("panic: %v", p)
+			}
+		}()
+		done <- hc.fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return errors.New("timed out after " + hc.timeout.String())
+	}
+}
@@ -0,0 +1,93 @@
+package kai_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	app := kai.NewApp()
+	var dbDown atomic.Bool
+	app.Health(kai.HealthOptions{CacheTTL: -1}).
+		AddLivenessCheck("loop", func(ctx context.Context) error { return nil }).
+		AddReadinessCheck("db", func(ctx context.Context) error {
+			if dbDown.Load() {
+				return errors.New("connection refused")
+			}
+			return nil
+		})
+	client := kaitest.New(t, app)
+
+	client.GET("/livez").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Cache-Control", "no-store").
+		ExpectJSONPath("status", "ok").
+		ExpectJSONPath("checks.loop.status", "ok")
+	client.GET("/readyz").Do().ExpectStatus(http.StatusOK).ExpectJSONPath("checks.db.status", "ok")
+
+	dbDown.Store(true)
+	client.GET("/readyz").Do().
+		ExpectStatus(http.StatusServiceUnavailable).
+		ExpectJSONPath("status", "fail").
+		ExpectJSONPath("checks.db.error", "connection refused")
+	client.HEAD("/readyz").Do().ExpectStatus(http.StatusServiceUnavailable).ExpectBody("")
+	// A failing dependency does not make the process unhealthy.
+	client.GET("/livez").Do().ExpectStatus(http.StatusOK)
+}
+
+func TestHealthCheckTimeoutPanicAndCache(t *testing.T) {
+	app := kai.NewApp()
+	var calls atomic.Int32
+	app.Health(kai.HealthOptions{LivePath: "/health/live", ReadyPath: "/health/ready", CacheTTL: time.Hour}).
+		AddReadinessCheck("cached", func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		}).
+		AddReadinessCheck("stuck", func(ctx context.Context) error {
+			time.Sleep(time.Second) // ignores ctx on purpose
+			return nil
+		}, 20*time.Millisecond).
+		AddLivenessCheck("broken", func(ctx context.Context) error { panic("boom") })
+	client := kaitest.New(t, app)
+
+	client.GET("/health/ready").Do().
+		ExpectStatus(http.StatusServiceUnavailable).
+		ExpectJSONPath("checks.stuck.error", "timed out after 20ms")
+	client.GET("/health/ready").Do()
+	if n := calls.Load(); n != 1 {
+		t.Errorf("cached check ran %d times, want 1", n)
+	}
+	client.GET("/health/live").Do().
+		ExpectStatus(http.StatusServiceUnavailable).
+		ExpectJSONPath("checks.broken.error", "panic: boom")
+}
+
+func TestShutdownFailsReadiness(t *testing.T) {
+	app := kai.NewApp()
+	health := app.Health(kai.HealthOptions{ShutdownDelay: 20 * time.Millisecond})
+	client := kaitest.New(t, app)
+
+	client.GET("/readyz").Do().ExpectStatus(http.StatusOK)
+
+	start := time.Now()
+	if err := app.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("Shutdown returned after %v, before ShutdownDelay", elapsed)
+	}
+	if !health.ShuttingDown() {
+		t.Error("ShuttingDown() = false after Shutdown")
+	}
+	client.GET("/readyz").Do().
+		ExpectStatus(http.StatusServiceUnavailable).
+		ExpectJSONPath("shutting_down", true)
+	client.GET("/livez").Do().ExpectStatus(http.StatusOK)
+}
@@ -0,0 +1,199 @@
+package kai
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type HTMLOptions struct {
+	// Layout is executed for every page instead of the page itself; pages
+	// fill its {{block}}s with {{define}}. Leave empty to render pages directly.
+	Layout string
+	// Shared lists glob patterns for layouts and partials. Matching files are
+	// parsed into every page and are not pages themselves.
+	Shared []string
+	// FuncMap is made available to every template.
+	FuncMap template.FuncMap
+	// LeftDelim and RightDelim override the default "{{" and "}}".
+	LeftDelim  string
+	RightDelim string
+	// DevMode re-parses templates from the file system on every render.
+	DevMode bool
+}
+
+// LoadHTMLGlob loads page templates from disk. Template names, and the Shared
+// patterns, are relative to the directory part of pattern before its first
+// wildcard, e.g. "templates/*/*.html" names pages like "pages/home.html".
+func (a *App) LoadHTMLGlob(pattern string, opts ...HTMLOptions) error {
+	root, rel := splitGlobRoot(pattern)
+	return a.LoadHTMLFS(os.DirFS(root), rel, opts...)
+}
+
+// LoadHTMLFS loads page templates matching pattern from fsys (e.g. an embed.FS).
+// Template names are paths relative to the root of fsys.
+func (a *App) LoadHTMLFS(fsys fs.FS, pattern string, opts ...HTMLOptions) error {
+	r := &htmlRenderer{fsys: fsys, pattern: pattern}
+	if len(opts) > 0 {
+		r.opts = opts[0]
+	}
+
+	set, err := r.load()
+	if err != nil {
+		return err
+	}
+	r.set = set
+	a.Router.htmlRender = r
+	return nil
+}
+
+func splitGlobRoot(pattern string) (string, string) {
+	pattern = filepath.ToSlash(pattern)
+	meta := strings.IndexAny(pattern, "*?[\\")
+	if meta < 0 {
+		meta = len(pattern)
+	}
+	slash := strings.LastIndex(pattern[:meta], "/")
+	if slash < 0 {
+		return ".", pattern
+	}
+	return pattern[:slash], pattern[slash+1:]
+}
+
+type htmlRenderer struct {
+	fsys    fs.FS
+	pattern string
+	opts    HTMLOptions
+	set     *htmlTemplateSet
+}
+
+type htmlTemplateSet struct {
+	shared *template.Template
+	pages  map[string]*template.Template
+}
+
+func (r *htmlRenderer) load() (*htmlTemplateSet, error) {
+	sharedNames := map[string]bool{}
+	var sharedFiles []string
+	for _, p := range r.opts.Shared {
+		matches, err := fs.Glob(r.fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			if !sharedNames[m] {
+				sharedNames[m] = true
+				sharedFiles = append(sharedFiles, m)
+			}
+		}
+	}
+
+	matches, err := fs.Glob(r.fsys, r.pattern)
+	if err != nil {
+		return nil, err
+	}
+	var pageFiles []string
+	for _, m := range matches {
+		if !sharedNames[m] {
+			pageFiles = append(pageFiles, m)
+		}
+	}
+	if len(pageFiles) == 0 && len(sharedFiles) == 0 {
+		return nil, fmt.Errorf("kai: no templates match %q", r.pattern)
+	}
+
+	shared := template.New("").Delims(r.opts.LeftDelim, r.opts.RightDelim)
+	if r.opts.FuncMap != nil {
+		shared = shared.Funcs(r.opts.FuncMap)
+	}
+	for _, name := range sharedFiles {
+		if err := r.parseFile(shared, name); err != nil {
+			return nil, err
+		}
+	}
+
+	set := &htmlTemplateSet{shared: shared, pages: make(map[string]*template.Template, len(pageFiles))}
+	for _, name := range pageFiles {
+		page, err := shared.Clone()
+		if err != nil {
+			return nil, err
+		}
+		if err := r.parseFile(page, name); err != nil {
+			return nil, err
+		}
+		set.pages[name] = page
+	}
+	return set, nil
+}
+
+func (r *htmlRenderer) parseFile(t *template.Template, name string) error {
+	info, err := fs.Stat(r.fsys, name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	text, err := fs.ReadFile(r.fsys, name)
+	if err != nil {
+		return err
+	}
+	if _, err := t.New(name).Parse(string(text)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *htmlRenderer) render(w io.Writer, name string, data any) error {
+	var set *htmlTemplateSet
+	if r.opts.DevMode {
+		fresh, err := r.load()
+		if err != nil {
+			return err
+		}
+		set = fresh
+	} else {
+		set = r.set
+	}
+
+	if page, ok := set.pages[name]; ok {
+		target := name
+		if r.opts.Layout != "" {
+			target = r.opts.Layout
+		}
+		return page.ExecuteTemplate(w, target, data)
+	}
+	// Layouts and partials can be rendered on their own (e.g. HTML fragments).
+	if t := set.shared.Lookup(name); t != nil {
+		return t.Execute(w, data)
+	}
+	return fmt.Errorf("kai: html template %q not found", name)
+}
+
+// HTML renders the named template with data. Output is buffered so a template
+// error results in a clean 500 instead of a half-written page.
+func (c *Context) HTML(code int, name string, data any) {
+	if c.router == nil || c.router.htmlRender == nil {
+		c.AddError(errors.New("kai: HTML templates not loaded, call LoadHTMLGlob or LoadHTMLFS"))
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "Internal Server Error"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := c.router.htmlRender.render(&buf, name, data); err != nil {
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "Internal Server Error"})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Status(code)
+	c.Write(buf.Bytes())
+}
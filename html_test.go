@@ -0,0 +1,88 @@
+package kai_test
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestHTMLLayoutsAndPartials(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{
+		"layouts/base.html":  `<title>{{block "title" .}}Kai{{end}}</title><main>{{block "content" .}}{{end}}</main>`,
+		"partials/nav.html":  `{{define "nav"}}<nav>{{upper .User}}</nav>{{end}}`,
+		"pages/home.html":    `{{define "title"}}Home{{end}}{{define "content"}}{{template "nav" .}}hi {{.User}}{{end}}`,
+		"pages/about.html":   `{{define "content"}}about{{end}}`,
+		"pages/broken.html":  `{{define "content"}}{{fail}}{{end}}`,
+		"fragments/row.html": `unused`,
+	})
+	app := kai.NewApp()
+	err := app.LoadHTMLGlob(filepath.Join(dir, "*/*.html"), kai.HTMLOptions{
+		Layout: "layouts/base.html",
+		Shared: []string{"layouts/*.html", "partials/*.html"},
+		FuncMap: template.FuncMap{
+			"upper": strings.ToUpper,
+			"fail":  func() (string, error) { return "", errors.New("boom") },
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	app.GET("/:page", func(c *kai.Context) {
+		c.HTML(http.StatusOK, "pages/"+c.Param("page")+".html", map[string]any{"User": "<kai>"})
+	})
+	app.GET("/partial/nav", func(c *kai.Context) { c.HTML(http.StatusOK, "nav", map[string]any{"User": "kai"}) })
+	client := kaitest.New(t, app)
+
+	client.GET("/home").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "text/html; charset=utf-8").
+		ExpectBody(`<title>Home</title><main><nav>&lt;KAI&gt;</nav>hi &lt;kai&gt;</main>`)
+	client.GET("/about").Do().ExpectBody(`<title>Kai</title><main>about</main>`)
+	client.GET("/partial/nav").Do().ExpectBody(`<nav>KAI</nav>`)
+	client.GET("/missing").Do().ExpectStatus(http.StatusInternalServerError)
+	client.GET("/broken").Do().
+		ExpectStatus(http.StatusInternalServerError).
+		ExpectJSON(map[string]any{"error": "Internal Server Error"})
+}
+
+func TestHTMLDevModeReloads(t *testing.T) {
+	dir := writeTemplates(t, map[string]string{"pages/home.html": `v1`})
+	app := kai.NewApp()
+	if err := app.LoadHTMLGlob(filepath.Join(dir, "pages/*.html"), kai.HTMLOptions{DevMode: true}); err != nil {
+		t.Fatal(err)
+	}
+	app.GET("/", func(c *kai.Context) { c.HTML(http.StatusOK, "home.html", nil) })
+	client := kaitest.New(t, app)
+
+	client.GET("/").Do().ExpectBody("v1")
+	os.WriteFile(filepath.Join(dir, "pages/home.html"), []byte("v2"), 0o644)
+	client.GET("/").Do().ExpectBody("v2")
+}
+
+func TestHTMLWithoutTemplates(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/", func(c *kai.Context) { c.HTML(http.StatusOK, "home.html", nil) })
+	kaitest.New(t, app).GET("/").Do().ExpectStatus(http.StatusInternalServerError)
+}
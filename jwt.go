@@ -0,0 +1,636 @@
+package kai
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JWT algorithms supported by JWT and SignJWT.
+const (
+	JWTHS256 = "HS256"
+	JWTRS256 = "RS256"
+	JWTES256 = "ES256"
+	JWTEdDSA = "EdDSA"
+)
+
+// JWTClaims are the decoded claims of a verified token.
+type JWTClaims map[string]any
+
+// Subject returns the "sub" claim, or "".
+func (cl JWTClaims) Subject() string {
+	s, _ := cl["sub"].(string)
+	return s
+}
+
+// Roles returns the "roles" claim, or the "role" claim as a single role.
+func (cl JWTClaims) Roles() []string {
+	if role, ok := cl["role"].(string); ok {
+		return []string{role}
+	}
+	list, _ := cl["roles"].([]any)
+	roles := make([]string, 0, len(list))
+	for _, item := range list {
+		if role, ok := item.(string); ok {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Scopes returns the space-separated "scope" claim as a slice.
+func (cl JWTClaims) Scopes() []string {
+	s, _ := cl["scope"].(string)
+	return strings.Fields(s)
+}
+
+type JWTOptions struct {
+	// Secret verifies HS256 tokens.
+	Secret []byte
+	// PublicKey verifies RS256 (*rsa.PublicKey), ES256 (*ecdsa.PublicKey on
+	// P-256) or EdDSA (ed25519.PublicKey) tokens.
+	PublicKey crypto.PublicKey
+	// JWKSFile or JWKSURL load a JSON Web Key Set; the token's "kid" picks the key.
+	JWKSFile string
+	JWKSURL  string
+	// JWKSRefresh is how long a loaded key set is cached. Defaults to one
+	// hour. An unknown kid triggers an early reload. Early reloads and
+	// retries after a failed reload happen at most once a minute, and the
+	// last good keys are served meanwhile.
+	JWKSRefresh time.Duration
+
+	// Algorithms restricts the accepted "alg" values. Defaults to every
+	// algorithm the configured keys can verify.
+	Algorithms []string
+	// Issuer, when set, must equal the "iss" claim.
+	Issuer string
+	// Audience, when set, must appear in the "aud" claim.
+	Audience string
+	// Leeway allows for clock skew when checking "exp" and "nbf".
+	Leeway time.Duration
+	// RequireExpiry rejects tokens without an "exp" claim.
+	RequireExpiry bool
+
+	// Cookie, when set, is read for the token if there is no Authorization header.
+	Cookie string
+	// Optional lets requests without a token through; invalid tokens are still rejected.
+	Optional bool
+	// Realm is sent in the WWW-Authenticate challenge. Defaults to "api".
+	Realm string
+}
+
+const jwtKey = "kai.jwt"
+
+type verifiedJWT struct {
+	claims  JWTClaims
+	payload []byte
+}
+
+var (
+	errJWTMissing   = errors.New("missing bearer token")
+	errJWTMalformed = errors.New("malformed token")
+	errJWTSignature = errors.New("invalid signature")
+)
+
+// JWT authenticates requests carrying "Authorization: Bearer <token>". Valid
+// claims are available through c.JWTClaims and JWTClaimsAs, and c.Principal
+// carries the subject and roles; failures get a 401 with a WWW-Authenticate
+// challenge.
+func JWT(opts JWTOptions) HandlerFunc {
+	if opts.Realm == "" {
+		opts.Realm = "api"
+	}
+	v := &jwtVerifier{opts: opts}
+	if opts.JWKSFile != "" || opts.JWKSURL != "" {
+		v.jwks = &jwksCache{file: opts.JWKSFile, url: opts.JWKSURL, ttl: opts.JWKSRefresh}
+		if v.jwks.ttl <= 0 {
+			v.jwks.ttl = time.Hour
+		}
+	}
+	if opts.Secret == nil && opts.PublicKey == nil && v.jwks == nil {
+		panic("Kai JWT: one of Secret, PublicKey, JWKSFile or JWKSURL is required")
+	}
+	if len(v.opts.Algorithms) == 0 {
+		v.opts.Algorithms = v.defaultAlgorithms()
+	}
+
+	return func(c *Context) {
+		token := bearerToken(c.Request)
+		if token == "" && opts.Cookie != "" {
+			token, _ = c.Cookie(opts.Cookie)
+		}
+		if token == "" {
+			if opts.Optional {
+				c.SetAuthChallenge(fmt.Sprintf("Bearer realm=%q", opts.Realm))
+				c.Next()
+				return
+			}
+			jwtUnauthorized(c, opts.Realm, errJWTMissing)
+			return
+		}
+
+		verified, err := v.verify(c.Request, token)
+		if err != nil {
+			jwtUnauthorized(c, opts.Realm, err)
+			return
+		}
+		c.Set(jwtKey, verified)
+		c.SetPrincipal(&Principal{
+			ID:         verified.claims.Subject(),
+			Scheme:     "jwt",
+			Roles:      verified.claims.Roles(),
+			Attributes: verified.claims,
+		})
+		c.Next()
+	}
+}
+
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+func jwtUnauthorized(c *Context, realm string, err error) {
+	challenge := fmt.Sprintf("Bearer realm=%q", realm)
+	if err != errJWTMissing {
+		challenge += fmt.Sprintf(`, error="invalid_token", error_description=%q`, err.Error())
+	}
+	c.SetHeader("WWW-Authenticate", challenge)
+	c.JSON(http.StatusUnauthorized, map[string]any{
+		"error": err.Error(),
+	})
+	c.Abort()
+}
+
+// JWTClaims returns the claims verified by the JWT middleware, or nil.
+func (c *Context) JWTClaims() JWTClaims {
+	value, _ := c.Get(jwtKey)
+	if verified, ok := value.(*verifiedJWT); ok {
+		return verified.claims
+	}
+	return nil
+}
+
+// JWTClaimsAs decodes the verified token payload into T, typically a struct
+// with json tags for the claims the app cares about.
+func JWTClaimsAs[T any](c *Context) (T, error) {
+	var out T
+	value, _ := c.Get(jwtKey)
+	verified, ok := value.(*verifiedJWT)
+	if !ok {
+		return out, errors.New("kai: no verified JWT on this request")
+	}
+	err := json.Unmarshal(verified.payload, &out)
+	return out, err
+}
+
+// ---------------------------
+// Verification
+// ---------------------------
+
+type jwtVerifier struct {
+	opts JWTOptions
+	jwks *jwksCache
+}
+
+func (v *jwtVerifier) defaultAlgorithms() []string {
+	var algs []string
+	if v.opts.Secret != nil {
+		algs = append(algs, JWTHS256)
+	}
+	if v.opts.PublicKey != nil {
+		if alg := algorithmForKey(v.opts.PublicKey); alg != "" {
+			algs = append(algs, alg)
+		}
+	}
+	if v.jwks != nil {
+		algs = append(algs, JWTRS256, JWTES256, JWTEdDSA)
+	}
+	return algs
+}
+
+func (v *jwtVerifier) verify(r *http.Request, token string) (*verifiedJWT, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errJWTMalformed
+	}
+	rawHeader, err1 := b64.DecodeString(parts[0])
+	payload, err2 := b64.DecodeString(parts[1])
+	sig, err3 := b64.DecodeString(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return nil, errJWTMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, errJWTMalformed
+	}
+	if !slices.Contains(v.opts.Algorithms, header.Alg) {
+		return nil, fmt.Errorf("algorithm %q is not allowed", header.Alg)
+	}
+
+	key, err := v.keyFor(r, header.Alg, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, errJWTMalformed
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return &verifiedJWT{claims: claims, payload: payload}, nil
+}
+
+func (v *jwtVerifier) keyFor(r *http.Request, alg, kid string) (any, error) {
+	if alg == JWTHS256 {
+		if v.opts.Secret == nil {
+			return nil, errJWTSignature
+		}
+		return v.opts.Secret, nil
+	}
+	if v.opts.PublicKey != nil && algorithmForKey(v.opts.PublicKey) == alg {
+		return v.opts.PublicKey, nil
+	}
+	if v.jwks != nil {
+		return v.jwks.key(r, alg, kid)
+	}
+	return nil, errJWTSignature
+}
+
+func algorithmForKey(key any) string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return JWTRS256
+	case *ecdsa.PublicKey:
+		if k.Curve == elliptic.P256() {
+			return JWTES256
+		}
+	case ed25519.PublicKey:
+		return JWTEdDSA
+	}
+	return ""
+}
+
+func verifyJWTSignature(alg string, key any, signed, sig []byte) error {
+	sum := sha256.Sum256(signed)
+	switch alg {
+	case JWTHS256:
+		secret, _ := key.([]byte)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signed)
+		if hmac.Equal(sig, mac.Sum(nil)) {
+			return nil
+		}
+	case JWTRS256:
+		if pub, ok := key.(*rsa.PublicKey); ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig) == nil {
+			return nil
+		}
+	case JWTES256:
+		if pub, ok := key.(*ecdsa.PublicKey); ok && len(sig) == 64 {
+			r := new(big.Int).SetBytes(sig[:32])
+			s := new(big.Int).SetBytes(sig[32:])
+			if ecdsa.Verify(pub, sum[:], r, s) {
+				return nil
+			}
+		}
+	case JWTEdDSA:
+		if pub, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(pub, signed, sig) {
+			return nil
+		}
+	}
+	return errJWTSignature
+}
+
+func (v *jwtVerifier) validateClaims(claims JWTClaims) error {
+	now := time.Now()
+	exp, hasExp, err := numericDate(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if hasExp {
+		if now.After(exp.Add(v.opts.Leeway)) {
+			return errors.New("token has expired")
+		}
+	} else if v.opts.RequireExpiry {
+		return errors.New("token has no expiry")
+	}
+	nbf, hasNbf, err := numericDate(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if hasNbf && now.Add(v.opts.Leeway).Before(nbf) {
+		return errors.New("token is not valid yet")
+	}
+	if v.opts.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.opts.Issuer {
+			return errors.New("token issuer is not accepted")
+		}
+	}
+	if v.opts.Audience != "" && !audienceContains(claims["aud"], v.opts.Audience) {
+		return errors.New("token audience is not accepted")
+	}
+	return nil
+}
+
+// numericDate reads a NumericDate claim. A claim that is present but not a
+// number (a string, null, an object) is an error rather than "no limit", so
+// a malformed exp cannot make a token valid forever.
+func numericDate(claims JWTClaims, name string) (time.Time, bool, error) {
+	raw, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	seconds, ok := raw.(float64)
+	if !ok {
+		return time.Time{}, true, errors.New("token " + name + " claim is not a number")
+	}
+	return time.Unix(int64(seconds), 0), true, nil
+}
+
+func audienceContains(aud any, want string) bool {
+	switch a := aud.(type) {
+	case string:
+		return a == want
+	case []any:
+		for _, item := range a {
+			if s, ok := item.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ---------------------------
+// JWKS
+// ---------------------------
+
+type jwksCache struct {
+	file, url string
+	ttl       time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]jwkKey
+	fetched   time.Time
+	attempted time.Time
+	failed    bool          // the last refresh failed
+	inflight  chan struct{} // closed when the running refresh finishes
+}
+
+type jwkKey struct {
+	alg string
+	key any
+}
+
+// jwksRetry is the least time between early reloads for an unknown kid and
+// between retries after a failed refresh, so a failing JWKS endpoint is not
+// hit on every request.
+const jwksRetry = time.Minute
+
+// key finds the verification key for a token. Refreshes run in the
+// background, one at a time and never under the lock: a stale but known key
+// keeps being served, and only requests that have no usable key wait.
+func (j *jwksCache) key(r *http.Request, alg, kid string) (any, error) {
+	j.mu.Lock()
+	now := time.Now()
+	_, known := j.keys[kid]
+	missing := j.keys == nil || (kid != "" && !known)
+	stale := j.keys == nil || now.Sub(j.fetched) > j.ttl
+	throttled := !j.attempted.IsZero() && now.Sub(j.attempted) < jwksRetry
+	if j.inflight == nil && ((stale && !(j.failed && throttled)) || (missing && !throttled)) {
+		j.attempted = now
+		j.inflight = make(chan struct{})
+		go j.refresh(j.inflight)
+	}
+	if wait := j.inflight; wait != nil && missing {
+		j.mu.Unlock()
+		select {
+		case <-wait:
+		case <-r.Context().Done():
+			return nil, r.Context().Err()
+		}
+		j.mu.Lock()
+	}
+	keys := j.keys
+	j.mu.Unlock()
+
+	if keys == nil {
+		return nil, errors.New("signing keys are unavailable")
+	}
+	if kid != "" {
+		if k, ok := keys[kid]; ok && k.alg == alg {
+			return k.key, nil
+		}
+		return nil, errors.New("unknown signing key")
+	}
+	// Without a kid, accept the key set only if a single key fits the algorithm.
+	var match any
+	for _, k := range keys {
+		if k.alg == alg {
+			if match != nil {
+				return nil, errors.New("token has no kid")
+			}
+			match = k.key
+		}
+	}
+	if match == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	return match, nil
+}
+
+// refresh loads the key set detached from any request, so a client going
+// away does not cancel the fetch other requests are waiting on. A failed
+// load keeps the last good keys.
+func (j *jwksCache) refresh(done chan struct{}) {
+	keys, err := j.load()
+	j.mu.Lock()
+	if err == nil {
+		j.keys = keys
+		j.fetched = time.Now()
+	}
+	j.failed = err != nil
+	j.inflight = nil
+	j.mu.Unlock()
+	close(done)
+}
+
+func (j *jwksCache) load() (map[string]jwkKey, error) {
+	var data []byte
+	var err error
+	if j.file != "" {
+		data, err = os.ReadFile(j.file)
+	} else {
+		data, err = fetchJWKS(j.url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+var jwksClient = &http.Client{Timeout: 10 * time.Second}
+
+func fetchJWKS(url string) ([]byte, error) {
+	resp, err := jwksClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kai: JWKS %s returned %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// parseJWKS parses a JSON Web Key Set into public keys by kid. RSA, EC P-256
+// and Ed25519 keys are supported; other keys are skipped.
+func parseJWKS(data []byte) (map[string]jwkKey, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("kai: parsing JWKS: %w", err)
+	}
+	keys := make(map[string]jwkKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch {
+		case k.Kty == "RSA":
+			n, err1 := b64.DecodeString(k.N)
+			e, err2 := b64.DecodeString(k.E)
+			if err1 != nil || err2 != nil || len(e) > 4 {
+				continue
+			}
+			pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+			keys[k.Kid] = jwkKey{alg: JWTRS256, key: pub}
+		case k.Kty == "EC" && k.Crv == "P-256":
+			x, err1 := b64.DecodeString(k.X)
+			y, err2 := b64.DecodeString(k.Y)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			uncompressed := append(append([]byte{4}, leftPad(x, 32)...), leftPad(y, 32)...)
+			pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), uncompressed)
+			if err != nil {
+				continue
+			}
+			keys[k.Kid] = jwkKey{alg: JWTES256, key: pub}
+		case k.Kty == "OKP" && k.Crv == "Ed25519":
+			x, err := b64.DecodeString(k.X)
+			if err != nil || len(x) != ed25519.PublicKeySize {
+				continue
+			}
+			keys[k.Kid] = jwkKey{alg: JWTEdDSA, key: ed25519.PublicKey(x)}
+		}
+	}
+	return keys, nil
+}
+
+func leftPad(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// ---------------------------
+// Signing
+// ---------------------------
+
+// SignJWT encodes claims as a compact JWS. The algorithm follows the key:
+// []byte for HS256, *rsa.PrivateKey for RS256, *ecdsa.PrivateKey (P-256)
+// for ES256 and ed25519.PrivateKey for EdDSA. kid, if given, is put in the header.
+func SignJWT(claims any, key any, kid ...string) (string, error) {
+	var alg string
+	switch k := key.(type) {
+	case []byte:
+		alg = JWTHS256
+	case *rsa.PrivateKey:
+		alg = JWTRS256
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return "", errors.New("kai: ES256 needs a P-256 key")
+		}
+		alg = JWTES256
+	case ed25519.PrivateKey:
+		alg = JWTEdDSA
+	default:
+		return "", fmt.Errorf("kai: unsupported JWT signing key %T", key)
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if len(kid) > 0 && kid[0] != "" {
+		header["kid"] = kid[0]
+	}
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := b64.EncodeToString(rawHeader) + "." + b64.EncodeToString(payload)
+	sum := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, sum[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case ed25519.PrivateKey:
+		sig = ed25519.Sign(k, []byte(signed))
+	}
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + b64.EncodeToString(sig), nil
+}
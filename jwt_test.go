@@ -0,0 +1,151 @@
+package kai_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func newJWTApp(opts kai.JWTOptions) *kai.App {
+	app := kai.NewApp()
+	app.GET("/me", kai.JWT(opts), func(c *kai.Context) {
+		c.JSON(http.StatusOK, map[string]any{"sub": c.JWTClaims().Subject(), "roles": c.Principal().Roles})
+	})
+	return app
+}
+
+func TestJWTSecret(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	client := kaitest.New(t, newJWTApp(kai.JWTOptions{Secret: secret, Issuer: "kai", RequireExpiry: true}))
+
+	token, err := kai.SignJWT(map[string]any{
+		"sub": "ada", "iss": "kai", "roles": []string{"admin"}, "exp": time.Now().Add(time.Minute).Unix(),
+	}, secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.GET("/me").BearerToken(token).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSON(map[string]any{"sub": "ada", "roles": []string{"admin"}})
+
+	expired, _ := kai.SignJWT(map[string]any{"sub": "ada", "iss": "kai", "exp": time.Now().Add(-time.Hour).Unix()}, secret)
+	wrongIssuer, _ := kai.SignJWT(map[string]any{"sub": "ada", "iss": "evil", "exp": time.Now().Add(time.Minute).Unix()}, secret)
+	noExpiry, _ := kai.SignJWT(map[string]any{"sub": "ada", "iss": "kai"}, secret)
+	forged, _ := kai.SignJWT(map[string]any{"sub": "ada", "iss": "kai", "exp": time.Now().Add(time.Minute).Unix()},
+		[]byte("another-secret-another-secret-32"))
+
+	for name, tok := range map[string]string{
+		"expired": expired, "issuer": wrongIssuer, "no exp": noExpiry, "forged": forged, "garbage": "a.b.c",
+	} {
+		res := client.GET("/me").BearerToken(tok).Do().ExpectStatus(http.StatusUnauthorized)
+		if res.Header.Get("WWW-Authenticate") == "" {
+			t.Errorf("%s: missing WWW-Authenticate", name)
+		}
+	}
+	client.GET("/me").Do().ExpectStatus(http.StatusUnauthorized)
+}
+
+func TestJWTRejectsMalformedTimeClaims(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	client := kaitest.New(t, newJWTApp(kai.JWTOptions{Secret: secret}))
+
+	valid, _ := kai.SignJWT(map[string]any{"sub": "ada", "nbf": time.Now().Add(-time.Minute).Unix()}, secret)
+	client.GET("/me").BearerToken(valid).Do().ExpectStatus(http.StatusOK)
+
+	for _, claim := range []string{"exp", "nbf"} {
+		for _, value := range []any{"1", nil, map[string]any{}, []any{1}} {
+			token, _ := kai.SignJWT(map[string]any{"sub": "ada", claim: value}, secret)
+			res := client.GET("/me").BearerToken(token).Do()
+			if res.Code != http.StatusUnauthorized {
+				t.Errorf("%s = %#v: status = %d, want 401", claim, value, res.Code)
+			}
+		}
+	}
+	notYet, _ := kai.SignJWT(map[string]any{"sub": "ada", "nbf": time.Now().Add(time.Hour).Unix()}, secret)
+	client.GET("/me").BearerToken(notYet).Do().ExpectStatus(http.StatusUnauthorized)
+}
+
+// jwksServer serves one Ed25519 key and counts fetches; fail makes it answer 500.
+type jwksServer struct {
+	*httptest.Server
+	hits atomic.Int64
+	fail atomic.Bool
+	priv ed25519.PrivateKey
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	s := &jwksServer{priv: priv}
+	body, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "OKP", "crv": "Ed25519", "kid": "k1", "x": base64.RawURLEncoding.EncodeToString(pub),
+	}}})
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.hits.Add(1)
+		if s.fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestJWTJWKSKeepsKeysWhileEndpointFails(t *testing.T) {
+	jwks := newJWKSServer(t)
+	client := kaitest.New(t, newJWTApp(kai.JWTOptions{JWKSURL: jwks.URL, JWKSRefresh: 10 * time.Millisecond}))
+	token, _ := kai.SignJWT(map[string]any{"sub": "ada"}, jwks.priv, "k1")
+
+	client.GET("/me").BearerToken(token).Do().ExpectStatus(http.StatusOK)
+	if n := jwks.hits.Load(); n != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", n)
+	}
+
+	jwks.fail.Store(true)
+	time.Sleep(20 * time.Millisecond) // past JWKSRefresh
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.GET("/me").BearerToken(token).Do().ExpectStatus(http.StatusOK)
+		}()
+	}
+	wg.Wait()
+	time.Sleep(20 * time.Millisecond) // let the background refresh finish
+	client.GET("/me").BearerToken(token).Do().ExpectStatus(http.StatusOK)
+	if n := jwks.hits.Load(); n != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2 (one failed refresh, then throttled)", n)
+	}
+
+	// An unknown kid does not retry within the throttle window either.
+	other, _ := kai.SignJWT(map[string]any{"sub": "ada"}, jwks.priv, "k2")
+	client.GET("/me").BearerToken(other).Do().ExpectStatus(http.StatusUnauthorized)
+	if n := jwks.hits.Load(); n != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", n)
+	}
+}
+
+func TestJWTJWKSUnavailable(t *testing.T) {
+	jwks := newJWKSServer(t)
+	jwks.fail.Store(true)
+	client := kaitest.New(t, newJWTApp(kai.JWTOptions{JWKSURL: jwks.URL}))
+	token, _ := kai.SignJWT(map[string]any{"sub": "ada"}, jwks.priv, "k1")
+
+	for range 3 {
+		client.GET("/me").BearerToken(token).Do().ExpectStatus(http.StatusUnauthorized)
+	}
+	if n := jwks.hits.Load(); n != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", n)
+	}
+}
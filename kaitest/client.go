@@ -0,0 +1,174 @@
+// Package kaitest provides helpers for testing Kai apps, routers, handlers
+// and middleware without starting a server.
+package kaitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// Client sends requests straight to an http.Handler (a *kai.App or *kai.Router)
+// and keeps cookies between requests, so multi-step flows like login work.
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+	jar     http.CookieJar
+	baseURL string
+	header  http.Header
+}
+
+// New returns a Client for handler. Failed expectations are reported on t.
+func New(t testing.TB, handler http.Handler) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{
+		t:       t,
+		handler: handler,
+		jar:     jar,
+		baseURL: "http://example.com",
+		header:  make(http.Header),
+	}
+}
+
+// HTTPS makes requests look like they arrived over TLS, so Secure cookies are kept.
+func (c *Client) HTTPS() *Client {
+	c.baseURL = "https://example.com"
+	return c
+}
+
+// SetHeader adds a header sent with every request from this client.
+func (c *Client) SetHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+// Jar returns the cookie jar shared by all requests.
+func (c *Client) Jar() http.CookieJar {
+	return c.jar
+}
+
+func (c *Client) GET(path string) *Request     { return c.Request(http.MethodGet, path) }
+func (c *Client) POST(path string) *Request    { return c.Request(http.MethodPost, path) }
+func (c *Client) PUT(path string) *Request     { return c.Request(http.MethodPut, path) }
+func (c *Client) PATCH(path string) *Request   { return c.Request(http.MethodPatch, path) }
+func (c *Client) DELETE(path string) *Request  { return c.Request(http.MethodDelete, path) }
+func (c *Client) HEAD(path string) *Request    { return c.Request(http.MethodHead, path) }
+func (c *Client) OPTIONS(path string) *Request { return c.Request(http.MethodOptions, path) }
+
+// Request starts building a request; call Do to send it.
+func (c *Client) Request(method, path string) *Request {
+	return &Request{
+		client: c,
+		method: method,
+		path:   path,
+		header: c.header.Clone(),
+		query:  make(url.Values),
+	}
+}
+
+// Request is a fluent request builder.
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	body    io.Reader
+	cookies []*http.Cookie
+}
+
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) Cookie(cookie *http.Cookie) *Request {
+	r.cookies = append(r.cookies, cookie)
+	return r
+}
+
+func (r *Request) BasicAuth(username, password string) *Request {
+	req := http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(username, password)
+	r.header.Set("Authorization", req.Header.Get("Authorization"))
+	return r
+}
+
+func (r *Request) BearerToken(token string) *Request {
+	r.header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// Body sets a raw body with the given content type.
+func (r *Request) Body(contentType string, body io.Reader) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// JSON encodes v as the request body.
+func (r *Request) JSON(v any) *Request {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.client.t.Helper()
+		r.client.t.Fatalf("kaitest: encoding JSON body: %v", err)
+	}
+	return r.Body("application/json", bytes.NewReader(data))
+}
+
+// Form sends values as an application/x-www-form-urlencoded body.
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body("application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+// Do runs the request through the handler and records the response.
+func (r *Request) Do() *Response {
+	c := r.client
+	c.t.Helper()
+
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, target, r.body)
+	for k, values := range r.header {
+		req.Header[k] = values
+	}
+	for _, cookie := range c.jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+
+	result := rec.Result()
+	c.jar.SetCookies(req.URL, result.Cookies())
+
+	return &Response{
+		t:        c.t,
+		Code:     rec.Code,
+		Header:   rec.Header(),
+		Body:     rec.Body.Bytes(),
+		Request:  req,
+		Response: result,
+	}
+}
@@ -0,0 +1,37 @@
+package kaitest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/dipto-kainin/kai"
+)
+
+// NewRequest is httptest.NewRequest, re-exported so tests need one import.
+func NewRequest(method, target string, body io.Reader) *http.Request {
+	return httptest.NewRequest(method, target, body)
+}
+
+// NewContext builds a *kai.Context for unit-testing a single handler or
+// middleware. Set c.Params to simulate path parameters.
+func NewContext(req *http.Request) (*kai.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	return kai.NewContext(rec, req), rec
+}
+
+// NewAppContext is like NewContext but binds the context to app's router, so
+// helpers that need app configuration (such as c.HTML) work.
+func NewAppContext(app *kai.App, req *http.Request) (*kai.Context, *httptest.ResponseRecorder) {
+	rec := httptest.NewRecorder()
+	return app.Router.NewContext(rec, req), rec
+}
+
+// Run executes handlers against c the way the router does, so Next and
+// Abort behave as in production. Run a middleware followed by a stub
+// handler to observe whether the chain continued.
+func Run(c *kai.Context, handlers ...kai.HandlerFunc) {
+	c.Handlers = handlers
+	c.MiddlewareIndex = -1
+	c.Next()
+}
@@ -0,0 +1,175 @@
+package kaitest_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+// recorder captures failed expectations instead of failing the test, so the
+// assertions themselves can be tested.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func newEchoApp() *kai.App {
+	app := kai.NewApp()
+	echo := func(c *kai.Context) {
+		body, _ := c.BodyString()
+		c.JSON(http.StatusOK, map[string]any{
+			"method":  c.Request.Method,
+			"query":   c.Request.URL.RawQuery,
+			"header":  c.Header("X-Test"),
+			"auth":    c.Header("Authorization"),
+			"type":    c.Header("Content-Type"),
+			"body":    body,
+			"https":   c.Request.TLS != nil,
+			"items":   []string{"a", "b"},
+			"cookies": len(c.Request.Cookies()),
+		})
+	}
+	app.GET("/echo", echo)
+	app.POST("/echo", echo)
+	app.PUT("/echo", echo)
+	app.POST("/login", func(c *kai.Context) {
+		c.SetCookie("sid", "abc", kai.CookieOptions{HttpOnly: true})
+		c.Status(http.StatusNoContent)
+	})
+	app.GET("/me", func(c *kai.Context) {
+		sid, err := c.Cookie("sid")
+		if err != nil {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		c.String(http.StatusOK, sid)
+	})
+	return app
+}
+
+func TestRequestBuilder(t *testing.T) {
+	client := kaitest.New(t, newEchoApp()).SetHeader("X-Test", "shared")
+
+	client.PUT("/echo").Query("a", "1").Query("b", "2").BearerToken("tok").Body("text/plain", strings.NewReader("hi")).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectJSONPath("method", "PUT").
+		ExpectJSONPath("query", "a=1&b=2").
+		ExpectJSONPath("header", "shared").
+		ExpectJSONPath("auth", "Bearer tok").
+		ExpectJSONPath("body", "hi").
+		ExpectJSONPath("items.1", "b").
+		ExpectJSONPath("https", false)
+
+	client.POST("/echo").JSON(map[string]int{"n": 1}).Do().
+		ExpectJSONPath("type", "application/json").
+		ExpectJSONPath("body", `{"n":1}`)
+	client.POST("/echo").Form(url.Values{"x": {"1"}}).Do().
+		ExpectJSONPath("type", "application/x-www-form-urlencoded").
+		ExpectJSONPath("body", "x=1")
+	client.GET("/echo").BasicAuth("u", "p").Do().ExpectJSONPath("auth", "Basic dTpw")
+	client.GET("/echo").Header("X-Test", "override").Do().ExpectJSONPath("header", "override")
+	client.GET("/echo").Cookie(&http.Cookie{Name: "one", Value: "1"}).Do().ExpectJSONPath("cookies", 1)
+
+	kaitest.New(t, newEchoApp()).HTTPS().GET("/echo").Do().ExpectJSONPath("https", true)
+}
+
+func TestCookieJar(t *testing.T) {
+	client := kaitest.New(t, newEchoApp())
+
+	client.GET("/me").Do().ExpectStatus(http.StatusUnauthorized)
+	res := client.POST("/login").Do().ExpectStatus(http.StatusNoContent)
+	if c := res.Cookie("sid"); c == nil || !c.HttpOnly {
+		t.Fatalf("Cookie(sid) = %+v", c)
+	}
+	if res.Cookie("missing") != nil {
+		t.Error("Cookie should return nil for unset cookies")
+	}
+	client.GET("/me").Do().ExpectStatus(http.StatusOK).ExpectBody("abc")
+}
+
+func TestExpectationsReportMismatches(t *testing.T) {
+	rec := &recorder{TB: t}
+	res := kaitest.New(rec, newEchoApp()).GET("/echo").Do()
+
+	res.ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "application/json").
+		ExpectHeaderPresent("Content-Type").
+		ExpectBodyContains(`"method":"GET"`).
+		ExpectJSONPath("items.0", "a")
+	if len(rec.errors) != 0 {
+		t.Fatalf("matching expectations failed: %v", rec.errors)
+	}
+
+	res.ExpectStatus(http.StatusTeapot).
+		ExpectHeader("Content-Type", "text/plain").
+		ExpectHeaderPresent("X-Missing").
+		ExpectBody("nope").
+		ExpectBodyContains("absent").
+		ExpectJSON(map[string]any{"method": "GET"}).
+		ExpectJSONPath("items.5", "a").
+		ExpectJSONPath("method", "POST")
+	if len(rec.errors) != 8 {
+		t.Fatalf("got %d failures, want 8: %v", len(rec.errors), rec.errors)
+	}
+	if !strings.Contains(rec.errors[0], "status = 200, want 418") {
+		t.Errorf("status failure = %q", rec.errors[0])
+	}
+
+	var out struct{ Method string }
+	res.DecodeJSON(&out)
+	if out.Method != "GET" {
+		t.Errorf("DecodeJSON got %+v", out)
+	}
+}
+
+func TestExpectJSONIgnoresFormatting(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/", func(c *kai.Context) {
+		c.Writer.Header().Set("Content-Type", "application/json")
+		c.String(http.StatusOK, `{ "b": [1, 2.0], "a": {"x": null} }`)
+	})
+	kaitest.New(t, app).GET("/").Do().
+		ExpectJSON(map[string]any{"a": map[string]any{"x": nil}, "b": []int{1, 2}})
+}
+
+func TestRunMiddleware(t *testing.T) {
+	requireKey := func(c *kai.Context) {
+		if c.Header("X-Key") != "k" {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]any{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+	handler := func(c *kai.Context) { c.String(http.StatusOK, "user "+c.Param("id")) }
+
+	c, rec := kaitest.NewContext(kaitest.NewRequest(http.MethodGet, "/users/7", nil))
+	kaitest.Run(c, requireKey, handler)
+	if rec.Code != http.StatusForbidden || !c.IsAborted() {
+		t.Fatalf("code = %d, aborted = %v", rec.Code, c.IsAborted())
+	}
+
+	req := kaitest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set("X-Key", "k")
+	c, rec = kaitest.NewAppContext(kai.NewApp(), req)
+	c.Params = map[string]string{"id": "7"}
+	kaitest.Run(c, requireKey, handler)
+	if rec.Code != http.StatusOK || rec.Body.String() != "user 7" {
+		t.Fatalf("code = %d, body = %q", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,168 @@
+package kaitest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response is a recorded response with chainable expectations. Failed
+// expectations call t.Errorf so one run reports every mismatch.
+type Response struct {
+	t        testing.TB
+	Code     int
+	Header   http.Header
+	Body     []byte
+	Request  *http.Request
+	Response *http.Response
+}
+
+func (r *Response) String() string {
+	return string(r.Body)
+}
+
+// Cookie returns the named cookie set by the response, or nil.
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Response.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func (r *Response) ExpectStatus(code int) *Response {
+	r.t.Helper()
+	if r.Code != code {
+		r.t.Errorf("kaitest: %s %s: status = %d, want %d; body: %s", r.Request.Method, r.Request.URL.Path, r.Code, code, r.Body)
+	}
+	return r
+}
+
+func (r *Response) ExpectHeader(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(key); got != value {
+		r.t.Errorf("kaitest: header %s = %q, want %q", key, got, value)
+	}
+	return r
+}
+
+func (r *Response) ExpectHeaderPresent(key string) *Response {
+	r.t.Helper()
+	if len(r.Header.Values(key)) == 0 {
+		r.t.Errorf("kaitest: header %s is missing", key)
+	}
+	return r
+}
+
+func (r *Response) ExpectBody(body string) *Response {
+	r.t.Helper()
+	if string(r.Body) != body {
+		r.t.Errorf("kaitest: body = %q, want %q", r.Body, body)
+	}
+	return r
+}
+
+func (r *Response) ExpectBodyContains(substr string) *Response {
+	r.t.Helper()
+	if !bytes.Contains(r.Body, []byte(substr)) {
+		r.t.Errorf("kaitest: body %q does not contain %q", r.Body, substr)
+	}
+	return r
+}
+
+// ExpectJSON compares the body with expected after decoding both into generic
+// JSON values, so key order and number formatting do not matter.
+func (r *Response) ExpectJSON(expected any) *Response {
+	r.t.Helper()
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		r.t.Errorf("kaitest: encoding expected JSON: %v", err)
+		return r
+	}
+	var got any
+	if err := json.Unmarshal(r.Body, &got); err != nil {
+		r.t.Errorf("kaitest: response is not JSON: %v; body: %s", err, r.Body)
+		return r
+	}
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("kaitest: JSON body = %s, want %s", r.Body, mustJSON(want))
+	}
+	return r
+}
+
+// ExpectJSONPath checks one value addressed by a dot path such as
+// "item.tags.0", where numeric segments index into arrays.
+func (r *Response) ExpectJSONPath(path string, expected any) *Response {
+	r.t.Helper()
+	var doc any
+	if err := json.Unmarshal(r.Body, &doc); err != nil {
+		r.t.Errorf("kaitest: response is not JSON: %v; body: %s", err, r.Body)
+		return r
+	}
+	got, ok := lookupJSONPath(doc, path)
+	if !ok {
+		r.t.Errorf("kaitest: JSON path %q not found in %s", path, r.Body)
+		return r
+	}
+	want, err := normalizeJSON(expected)
+	if err != nil {
+		r.t.Errorf("kaitest: encoding expected JSON: %v", err)
+		return r
+	}
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("kaitest: JSON path %q = %s, want %s", path, mustJSON(got), mustJSON(want))
+	}
+	return r
+}
+
+// DecodeJSON unmarshals the body into v.
+func (r *Response) DecodeJSON(v any) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Errorf("kaitest: decoding JSON body: %v; body: %s", err, r.Body)
+	}
+	return r
+}
+
+func normalizeJSON(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(data, &out)
+	return out, err
+}
+
+func lookupJSONPath(doc any, path string) (any, bool) {
+	current := doc
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			current = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func mustJSON(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
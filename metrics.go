@@ -0,0 +1,298 @@
+package kai
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDurationBuckets are the request duration histogram buckets, in seconds.
+var DefaultDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the response size histogram buckets, in bytes.
+var DefaultSizeBuckets = []float64{100, 1000, 10_000, 100_000, 1_000_000, 10_000_000}
+
+type MetricsOptions struct {
+	// Namespace prefixes every metric name. Defaults to "kai".
+	Namespace string
+	// DurationBuckets and SizeBuckets override the histogram buckets.
+	DurationBuckets []float64
+	SizeBuckets     []float64
+}
+
+// Metrics records per-route HTTP metrics and serves them in the Prometheus
+// text exposition format. Series are labeled by method ("OTHER" for
+// non-standard ones), route pattern (c.FullPath, or "unmatched" for 404s so
+// unknown URLs cannot blow up the series count) and status.
+type Metrics struct {
+	opts MetricsOptions
+
+	inFlight atomic.Int64
+
+	mu     sync.Mutex
+	series map[metricLabels]*requestSeries
+}
+
+type metricLabels struct {
+	method, route, status string
+}
+
+type requestSeries struct {
+	count    uint64
+	duration histogram
+	size     histogram
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative; the last one is +Inf
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(buckets)+1)
+	}
+	i := sort.SearchFloat64s(buckets, v)
+	h.counts[i]++
+	h.sum += v
+}
+
+func NewMetrics(opts ...MetricsOptions) *Metrics {
+	o := firstOr(opts)
+	if o.Namespace == "" {
+		o.Namespace = "kai"
+	}
+	if o.DurationBuckets == nil {
+		o.DurationBuckets = DefaultDurationBuckets
+	}
+	if o.SizeBuckets == nil {
+		o.SizeBuckets = DefaultSizeBuckets
+	}
+	o.DurationBuckets = sortedCopy(o.DurationBuckets)
+	o.SizeBuckets = sortedCopy(o.SizeBuckets)
+	return &Metrics{
+		opts:   o,
+		series: make(map[metricLabels]*requestSeries),
+	}
+}
+
+func sortedCopy(values []float64) []float64 {
+	out := append([]float64(nil), values...)
+	sort.Float64s(out)
+	return out
+}
+
+// Middleware records every request passing through it. Register it first
+// with app.Use so it also sees requests rejected by other middleware.
+func (m *Metrics) Middleware() HandlerFunc {
+	return func(c *Context) {
+		start := time.Now()
+		m.inFlight.Add(1)
+		mw := &statusWriter{ResponseWriter: c.Writer}
+		c.Writer = mw
+
+		defer func() {
+			c.Writer = mw.ResponseWriter
+			m.inFlight.Add(-1)
+			status := mw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if p := recover(); p != nil {
+				m.observe(c, http.StatusInternalServerError, time.Since(start), mw.size)
+				panic(p)
+			}
+			m.observe(c, status, time.Since(start), mw.size)
+		}()
+		c.Next()
+	}
+}
+
+func (m *Metrics) observe(c *Context, status int, elapsed time.Duration, size int64) {
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	labels := metricLabels{method: metricMethod(c.Request.Method), route: route, status: strconv.Itoa(status)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[labels]
+	if !ok {
+		s = &requestSeries{}
+		m.series[labels] = s
+	}
+	s.count++
+	s.duration.observe(m.opts.DurationBuckets, elapsed.Seconds())
+	s.size.observe(m.opts.SizeBuckets, float64(size))
+}
+
+// metricMethod maps methods outside the standard set to "OTHER", so clients
+// cannot create series by inventing methods.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// Handler serves the metrics, typically at GET /metrics.
+func (m *Metrics) Handler() HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		m.WriteTo(c.Writer)
+	}
+}
+
+// WriteTo writes every metric in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	labels := make([]metricLabels, 0, len(m.series))
+	snapshot := make(map[metricLabels]requestSeries, len(m.series))
+	for l, s := range m.series {
+		labels = append(labels, l)
+		snapshot[l] = requestSeries{
+			count:    s.count,
+			duration: histogram{counts: append([]uint64(nil), s.duration.counts...), sum: s.duration.sum},
+			size:     histogram{counts: append([]uint64(nil), s.size.counts...), sum: s.size.sum},
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(labels, func(i, j int) bool {
+		a, b := labels[i], labels[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	ns := m.opts.Namespace
+
+	name := ns + "_http_requests_total"
+	writeMetricHeader(bw, name, "counter", "Total number of HTTP requests.")
+	for _, l := range labels {
+		fmt.Fprintf(bw, "%s{%s} %d\n", name, l.String(), snapshot[l].count)
+	}
+
+	name = ns + "_http_request_duration_seconds"
+	writeMetricHeader(bw, name, "histogram", "HTTP request latency in seconds.")
+	for _, l := range labels {
+		s := snapshot[l]
+		writeHistogram(bw, name, l.String(), m.opts.DurationBuckets, s.duration, s.count)
+	}
+
+	name = ns + "_http_response_size_bytes"
+	writeMetricHeader(bw, name, "histogram", "HTTP response body size in bytes.")
+	for _, l := range labels {
+		s := snapshot[l]
+		writeHistogram(bw, name, l.String(), m.opts.SizeBuckets, s.size, s.count)
+	}
+
+	name = ns + "_http_requests_in_flight"
+	writeMetricHeader(bw, name, "gauge", "HTTP requests currently being served.")
+	fmt.Fprintf(bw, "%s %d\n", name, m.inFlight.Load())
+
+	err := bw.Flush()
+	return cw.n, err
+}
+
+func writeMetricHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w io.Writer, name, labels string, buckets []float64, h histogram, count uint64) {
+	var cumulative uint64
+	for i, le := range buckets {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", name, labels, formatFloat(le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, labels, count)
+	fmt.Fprintf(w, "%s_sum{%s} %s\n", name, labels, formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count{%s} %d\n", name, labels, count)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func (l metricLabels) String() string {
+	return fmt.Sprintf(`method="%s",route="%s",status="%s"`,
+		escapeLabel(l.method), escapeLabel(l.route), escapeLabel(l.status))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// statusWriter captures the status code and body size of a response, for
+// middleware that reports on it after the handler ran.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *statusWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Metrics installs m's middleware globally and serves the metrics at path.
+// Pass middleware to protect the endpoint.
+func (a *App) Metrics(m *Metrics, path string, middleware ...HandlerFunc) {
+	a.Use(m.Middleware())
+	a.GET(path, append(append([]HandlerFunc{}, middleware...), m.Handler())...)
+}
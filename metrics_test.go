@@ -0,0 +1,59 @@
+package kai_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestMetrics(t *testing.T) {
+	app := kai.NewApp()
+	metrics := kai.NewMetrics()
+	app.Metrics(metrics, "/metrics")
+	app.GET("/users/:id", func(c *kai.Context) { c.String(http.StatusOK, "user") })
+	client := kaitest.New(t, app)
+
+	client.GET("/users/1").Do()
+	client.GET("/users/2").Do()
+	client.GET("/nope/1").Do().ExpectStatus(http.StatusNotFound)
+	client.GET("/nope/2").Do()
+
+	body := client.GET("/metrics").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Content-Type", "text/plain; version=0.0.4; charset=utf-8").
+		String()
+	for _, want := range []string{
+		`kai_http_requests_total{method="GET",route="/users/:id",status="200"} 2`,
+		`kai_http_requests_total{method="GET",route="unmatched",status="404"} 2`,
+		`kai_http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 2`,
+		`kai_http_response_size_bytes_bucket{method="GET",route="/users/:id",status="200",le="100"} 2`,
+		"kai_http_requests_in_flight 1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsNonStandardMethods(t *testing.T) {
+	app := kai.NewApp()
+	metrics := kai.NewMetrics()
+	app.Metrics(metrics, "/metrics")
+	client := kaitest.New(t, app)
+
+	for _, method := range []string{"AAA", "BBB", "CCC"} {
+		client.Request(method, "/x").Do()
+	}
+
+	var out strings.Builder
+	metrics.WriteTo(&out)
+	if n := strings.Count(out.String(), "kai_http_requests_total{"); n != 1 {
+		t.Fatalf("got %d request series, want 1:\n%s", n, out.String())
+	}
+	if !strings.Contains(out.String(), `kai_http_requests_total{method="OTHER",route="unmatched",status="404"} 3`) {
+		t.Fatalf("missing OTHER series:\n%s", out.String())
+	}
+}
@@ -1,7 +1,6 @@
 package kai
 
 import (
-	"compress/gzip"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
@@ -11,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/dipto-kainin/kai/utils"
 )
 
 func DamageControl() HandlerFunc {
@@ -220,19 +221,100 @@ func Pain_of_CORS(opts ...CORSOptions) HandlerFunc {
     }
 }
 
-func RequestID() HandlerFunc {
+type RequestIDOptions struct {
+	// Header is read for an inbound ID and set on the response. Defaults to "X-Request-ID".
+	Header string
+	// Generator creates IDs. Defaults to RequestIDHex; RequestIDUUIDv4,
+	// RequestIDUUIDv7 and RequestIDULID are also provided.
+	Generator func() string
+	// Validator decides whether an inbound ID is kept. Defaults to 1-128
+	// characters of letters, digits and "-_.:+/=".
+	Validator func(id string) bool
+	// IgnoreInbound always generates a fresh ID, e.g. at a public edge where
+	// clients must not choose the ID.
+	IgnoreInbound bool
+}
+
+// RequestID tags each request with an ID, reusing a valid inbound one so a
+// request keeps its ID across services. The ID is echoed in the response
+// header and available from c.RequestID and RequestIDFromContext.
+func RequestID(opts ...RequestIDOptions) HandlerFunc {
+	o := firstOr(opts)
+	if o.Header == "" {
+		o.Header = "X-Request-ID"
+	}
+	if o.Generator == nil {
+		o.Generator = RequestIDHex
+	}
+	if o.Validator == nil {
+		o.Validator = validRequestID
+	}
+
 	return func(c *Context) {
-		id := make([]byte, 16)
-		_, err := rand.Read(id)
-		if err != nil {
-			id = []byte(fmt.Sprintf("%d", time.Now().UnixNano()))
+		rid := ""
+		if !o.IgnoreInbound {
+			if inbound := c.Request.Header.Get(o.Header); inbound != "" && o.Validator(inbound) {
+				rid = inbound
+			}
 		}
-		rid := hex.EncodeToString(id)
-		c.Writer.Header().Set("X-Request-ID", rid)
+		if rid == "" {
+			rid = o.Generator()
+		}
+		c.Writer.Header().Set(o.Header, rid)
 		c.Set("RequestID", rid)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDContextKey{}, rid))
 		c.Next()
 	}
 }
+
+type requestIDContextKey struct{}
+
+// RequestIDFromContext returns the ID set by the RequestID middleware, so
+// code that only has a context.Context (loggers, outgoing clients) can use it.
+func RequestIDFromContext(ctx context.Context) string {
+	rid, _ := ctx.Value(requestIDContextKey{}).(string)
+	return rid
+}
+
+// RequestID returns the ID set by the RequestID middleware, or "".
+func (c *Context) RequestID() string {
+	rid, _ := c.Get("RequestID")
+	s, _ := rid.(string)
+	return s
+}
+
+// RequestIDHex generates 32 random hex characters.
+func RequestIDHex() string {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
+
+func RequestIDUUIDv4() string { return utils.NewUUIDv4().String() }
+
+// RequestIDUUIDv7 generates time-ordered UUIDs, handy as database keys.
+func RequestIDUUIDv7() string { return utils.NewUUIDv7().String() }
+
+func RequestIDULID() string { return utils.NewULID() }
+
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		switch {
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+		case strings.IndexByte("-_.:+/=", ch) >= 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
 // Timeout enforces a timeout for the request handler chain.
 // When timeout occurs, sends 504 Gateway Timeout and aborts the chain.
 // 
@@ -339,86 +421,6 @@ func clientIP(r *http.Request) string {
 	}
 	return host
 }
-func SecureHeaders() HandlerFunc {
-	return func(c *Context) {
-		h := c.Writer.Header()
-		h.Set("X-Content-Type-Options", "nosniff")
-		h.Set("X-Frame-Options", "DENY")
-		h.Set("X-XSS-Protection", "1; mode=block")
-		h.Set("Referrer-Policy", "no-referrer")
-		h.Set("Permissions-Policy", "geolocation=(), microphone=()")
-		h.Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
-		h.Set("Content-Security-Policy", "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data: https:; font-src 'self'; connect-src 'self'; frame-ancestors 'none'")
-		c.Next()
-	}
-}
-func GZip(level int) HandlerFunc {
-	return func(c *Context) {
-		ae := c.Request.Header.Get("Accept-Encoding")
-		if !strings.Contains(ae, "gzip") {
-			c.Next()
-			return
-		}
-		// Initialize gzip writer with the actual ResponseWriter (not nil)
-		gw, err := gzip.NewWriterLevel(c.Writer, level)
-		if err != nil {
-			c.Next()
-			return
-		}
-
-		grw := &gzipResponseWriter{
-			ResponseWriter: c.Writer,
-			gz:             gw,
-			wroteHeader:    false,
-		}
-		c.Writer = grw
-		defer func() {
-			_ = grw.Close()
-		}()
-
-		c.Writer.Header().Set("Content-Encoding", "gzip")
-		c.Writer.Header().Del("Content-Length")
-
-		c.Next()
-	}
-}
-
-type gzipResponseWriter struct {
-	http.ResponseWriter
-	gz          *gzip.Writer
-	wroteHeader bool
-}
-
-func (w *gzipResponseWriter) WriteHeader(status int) {
-	if !w.wroteHeader {
-		w.ResponseWriter.WriteHeader(status)
-		w.wroteHeader = true
-	}
-}
-
-func (w *gzipResponseWriter) Write(p []byte) (int, error) {
-	if w.gz == nil {
-		w.gz = gzip.NewWriter(w.ResponseWriter)
-	}
-	return w.gz.Write(p)
-}
-
-// Flush flushes the gzip writer and underlying ResponseWriter if it supports flushing
-func (w *gzipResponseWriter) Flush() {
-	if w.gz != nil {
-		w.gz.Flush()
-	}
-	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
-		flusher.Flush()
-	}
-}
-
-func (w *gzipResponseWriter) Close() error {
-	if w.gz != nil {
-		return w.gz.Close()
-	}
-	return nil
-}
 func BodyLimit(maxBytes int64) HandlerFunc {
 	return func(c *Context) {
 		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
@@ -0,0 +1,40 @@
+package kai
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// ParamInt parses a path param as an int. The error is a 400 *utils.HTTPError.
+func (c *Context) ParamInt(key string) (int, error) {
+	v, err := strconv.Atoi(c.Param(key))
+	if err != nil {
+		return 0, paramError(key, "an integer", err)
+	}
+	return v, nil
+}
+
+// ParamInt64 parses a path param as an int64. The error is a 400 *utils.HTTPError.
+func (c *Context) ParamInt64(key string) (int64, error) {
+	v, err := strconv.ParseInt(c.Param(key), 10, 64)
+	if err != nil {
+		return 0, paramError(key, "an integer", err)
+	}
+	return v, nil
+}
+
+// ParamUUID parses a path param as a UUID. The error is a 400 *utils.HTTPError.
+func (c *Context) ParamUUID(key string) (utils.UUID, error) {
+	v, err := utils.ParseUUID(c.Param(key))
+	if err != nil {
+		return utils.UUID{}, paramError(key, "a UUID", err)
+	}
+	return v, nil
+}
+
+func paramError(key, kind string, err error) error {
+	return utils.WrapHTTPError(http.StatusBadRequest, fmt.Sprintf("path parameter %q must be %s", key, kind), err)
+}
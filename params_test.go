@@ -0,0 +1,74 @@
+package kai_test
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestParamConstraints(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/users/:id<int>", func(c *kai.Context) {
+		id, err := c.ParamInt("id")
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.JSON(http.StatusOK, map[string]int{"id": id})
+	})
+	app.GET("/users/:name<alpha>", func(c *kai.Context) { c.String(http.StatusOK, "name "+c.Param("name")) })
+	app.GET("/tags/:slug<[a-z-]+>", func(c *kai.Context) { c.String(http.StatusOK, c.Param("slug")) })
+	app.GET("/items/:id<uuid>", func(c *kai.Context) {
+		id, err := c.ParamUUID("id")
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.String(http.StatusOK, id.String())
+	})
+	client := kaitest.New(t, app)
+
+	client.GET("/users/42").Do().ExpectStatus(http.StatusOK).ExpectJSON(map[string]int{"id": 42})
+	client.GET("/users/-3").Do().ExpectJSON(map[string]int{"id": -3})
+	client.GET("/users/alice").Do().ExpectBody("name alice")
+	client.GET("/users/al1ce").Do().ExpectStatus(http.StatusNotFound)
+	client.GET("/tags/go-lang").Do().ExpectBody("go-lang")
+	client.GET("/tags/Go").Do().ExpectStatus(http.StatusNotFound)
+
+	id := "0190a0f1-3c4d-7e8f-9a0b-1c2d3e4f5a6b"
+	client.GET("/items/" + id).Do().ExpectStatus(http.StatusOK).ExpectBody(id)
+	client.GET("/items/not-a-uuid").Do().ExpectStatus(http.StatusNotFound)
+
+	// The pattern accepts any digits; ParamInt still rejects values that overflow.
+	client.GET("/users/" + strconv.Itoa(1<<62) + "0000").Do().
+		ExpectStatus(http.StatusBadRequest).
+		ExpectJSON(map[string]string{"error": `path parameter "id" must be an integer`})
+}
+
+func TestParamTypedAccessors(t *testing.T) {
+	c, _ := kaitest.NewContext(kaitest.NewRequest(http.MethodGet, "/", nil))
+	c.Params["n"] = "9000000000"
+	c.Params["bad"] = "x"
+
+	if v, err := c.ParamInt64("n"); err != nil || v != 9000000000 {
+		t.Errorf("ParamInt64 = %d, %v", v, err)
+	}
+	if _, err := c.ParamInt("bad"); err == nil {
+		t.Error("ParamInt accepted a non-integer")
+	}
+	if _, err := c.ParamUUID("bad"); err == nil {
+		t.Error("ParamUUID accepted a non-UUID")
+	}
+}
+
+func TestParamConstraintInvalidPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an invalid constraint")
+		}
+	}()
+	kai.NewApp().GET("/users/:id<[0-9>", func(c *kai.Context) {})
+}
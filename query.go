@@ -0,0 +1,156 @@
+package kai
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// queryValues parses the query string once per request.
+func (c *Context) queryValues() url.Values {
+	if c.queryCache == nil {
+		c.queryCache = c.Request.URL.Query()
+	}
+	return c.queryCache
+}
+
+// GetQuery returns the first value for key and whether the key was present,
+// so "?q=" can be told apart from a missing q.
+func (c *Context) GetQuery(key string) (string, bool) {
+	values, ok := c.queryValues()[key]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// QueryArray returns every value for key, accepting both "?tag=a&tag=b"
+// and "?tag[]=a&tag[]=b".
+func (c *Context) QueryArray(key string) []string {
+	values, _ := c.GetQueryArray(key)
+	return values
+}
+
+func (c *Context) GetQueryArray(key string) ([]string, bool) {
+	query := c.queryValues()
+	plain, okPlain := query[key]
+	brackets, okBrackets := query[key+"[]"]
+	if !okPlain && !okBrackets {
+		return []string{}, false
+	}
+	values := make([]string, 0, len(plain)+len(brackets))
+	values = append(values, plain...)
+	return append(values, brackets...), true
+}
+
+// QueryMap collects "?filter[status]=open&filter[owner]=me" into
+// {"status": "open", "owner": "me"} for key "filter".
+func (c *Context) QueryMap(key string) map[string]string {
+	m, _ := c.GetQueryMap(key)
+	return m
+}
+
+func (c *Context) GetQueryMap(key string) (map[string]string, bool) {
+	m := make(map[string]string)
+	prefix := key + "["
+	for k, values := range c.queryValues() {
+		if !strings.HasPrefix(k, prefix) || !strings.HasSuffix(k, "]") || len(values) == 0 {
+			continue
+		}
+		sub := k[len(prefix) : len(k)-1]
+		if sub == "" || strings.ContainsAny(sub, "[]") {
+			continue
+		}
+		m[sub] = values[0]
+	}
+	return m, len(m) > 0
+}
+
+// QueryInt parses key as an int. A missing or empty value yields the default
+// (or 0) with no error; an invalid one yields the default and a 400 *utils.HTTPError.
+func (c *Context) QueryInt(key string, def ...int) (int, error) {
+	fallback := firstOr(def)
+	raw, ok := c.GetQuery(key)
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return fallback, queryError(key, "an integer", err)
+	}
+	return v, nil
+}
+
+func (c *Context) QueryInt64(key string, def ...int64) (int64, error) {
+	fallback := firstOr(def)
+	raw, ok := c.GetQuery(key)
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+	v, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return fallback, queryError(key, "an integer", err)
+	}
+	return v, nil
+}
+
+func (c *Context) QueryFloat64(key string, def ...float64) (float64, error) {
+	fallback := firstOr(def)
+	raw, ok := c.GetQuery(key)
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fallback, queryError(key, "a number", err)
+	}
+	return v, nil
+}
+
+// QueryBool accepts the values understood by strconv.ParseBool.
+func (c *Context) QueryBool(key string, def ...bool) (bool, error) {
+	fallback := firstOr(def)
+	raw, ok := c.GetQuery(key)
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		return fallback, queryError(key, "true or false", err)
+	}
+	return v, nil
+}
+
+// QueryTime parses key with layout, or time.RFC3339 when layout is empty.
+func (c *Context) QueryTime(key, layout string, def ...time.Time) (time.Time, error) {
+	fallback := firstOr(def)
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	raw, ok := c.GetQuery(key)
+	if !ok || raw == "" {
+		return fallback, nil
+	}
+	v, err := time.Parse(layout, raw)
+	if err != nil {
+		return fallback, queryError(key, "a time in "+layout+" format", err)
+	}
+	return v, nil
+}
+
+func firstOr[T any](values []T) T {
+	var zero T
+	if len(values) > 0 {
+		return values[0]
+	}
+	return zero
+}
+
+func queryError(key, kind string, err error) error {
+	return utils.WrapHTTPError(http.StatusBadRequest, fmt.Sprintf("query parameter %q must be %s", key, kind), err)
+}
@@ -0,0 +1,93 @@
+package kai_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+	"github.com/dipto-kainin/kai/utils"
+)
+
+func queryContext(target string) *kai.Context {
+	c, _ := kaitest.NewContext(kaitest.NewRequest(http.MethodGet, target, nil))
+	return c
+}
+
+func TestQueryPresenceAndCollections(t *testing.T) {
+	c := queryContext("/?q=&tag=a&tag[]=b&filter[status]=open&filter[owner]=me&filter[]=x&filter[a][b]=y")
+
+	if v, ok := c.GetQuery("q"); !ok || v != "" {
+		t.Errorf("GetQuery(q) = %q, %v", v, ok)
+	}
+	if _, ok := c.GetQuery("missing"); ok {
+		t.Error("GetQuery reported a missing key as present")
+	}
+	if got := c.QueryArray("tag"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("QueryArray = %v", got)
+	}
+	if got, ok := c.GetQueryArray("none"); ok || got == nil || len(got) != 0 {
+		t.Errorf("GetQueryArray(none) = %#v, %v", got, ok)
+	}
+	want := map[string]string{"status": "open", "owner": "me"}
+	if got, ok := c.GetQueryMap("filter"); !ok || !reflect.DeepEqual(got, want) {
+		t.Errorf("GetQueryMap = %v, %v", got, ok)
+	}
+	if _, ok := c.GetQueryMap("tag"); ok {
+		t.Error("GetQueryMap(tag) reported a map")
+	}
+}
+
+func TestQueryTypedAccessors(t *testing.T) {
+	c := queryContext("/?page=3&big=9000000000&ratio=0.5&draft=true&since=2024-05-01T10:00:00Z&day=2024-05-01&empty=")
+
+	if v, err := c.QueryInt("page"); err != nil || v != 3 {
+		t.Errorf("QueryInt = %d, %v", v, err)
+	}
+	if v, err := c.QueryInt("empty", 20); err != nil || v != 20 {
+		t.Errorf("QueryInt(empty) = %d, %v", v, err)
+	}
+	if v, err := c.QueryInt64("big"); err != nil || v != 9000000000 {
+		t.Errorf("QueryInt64 = %d, %v", v, err)
+	}
+	if v, err := c.QueryFloat64("ratio"); err != nil || v != 0.5 {
+		t.Errorf("QueryFloat64 = %v, %v", v, err)
+	}
+	if v, err := c.QueryBool("draft"); err != nil || !v {
+		t.Errorf("QueryBool = %v, %v", v, err)
+	}
+	if v, err := c.QueryTime("since", ""); err != nil || !v.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("QueryTime = %v, %v", v, err)
+	}
+	if v, err := c.QueryTime("day", time.DateOnly); err != nil || v.Day() != 1 {
+		t.Errorf("QueryTime(DateOnly) = %v, %v", v, err)
+	}
+
+	v, err := c.QueryInt("ratio", 1)
+	var httpErr *utils.HTTPError
+	if v != 1 || !errors.As(err, &httpErr) || httpErr.Code != http.StatusBadRequest {
+		t.Errorf("QueryInt(ratio) = %d, %v; want the default and a 400", v, err)
+	}
+}
+
+func TestQueryErrorResponse(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/posts", func(c *kai.Context) {
+		page, err := c.QueryInt("page", 1)
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.JSON(http.StatusOK, map[string]int{"page": page})
+	})
+	client := kaitest.New(t, app)
+
+	client.GET("/posts").Do().ExpectJSON(map[string]int{"page": 1})
+	client.GET("/posts").Query("page", "4").Do().ExpectJSON(map[string]int{"page": 4})
+	client.GET("/posts").Query("page", "four").Do().
+		ExpectStatus(http.StatusBadRequest).
+		ExpectJSON(map[string]string{"error": `query parameter "page" must be an integer`})
+}
@@ -0,0 +1,73 @@
+package kai_test
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func echoRequestID(c *kai.Context) {
+	c.JSON(http.StatusOK, map[string]string{
+		"id":      c.RequestID(),
+		"context": kai.RequestIDFromContext(c.Request.Context()),
+	})
+}
+
+func TestRequestID(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.RequestID())
+	app.GET("/", echoRequestID)
+	client := kaitest.New(t, app)
+
+	res := client.GET("/").Do().ExpectStatus(http.StatusOK)
+	id := res.Header.Get("X-Request-ID")
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(id) {
+		t.Errorf("generated ID = %q", id)
+	}
+	res.ExpectJSON(map[string]string{"id": id, "context": id})
+
+	client.GET("/").Header("X-Request-ID", "upstream-42").Do().
+		ExpectHeader("X-Request-ID", "upstream-42").
+		ExpectJSONPath("context", "upstream-42")
+
+	for _, bad := range []string{"has space", "quote\"", strings.Repeat("a", 129)} {
+		if got := client.GET("/").Header("X-Request-ID", bad).Do().Header.Get("X-Request-ID"); got == bad {
+			t.Errorf("invalid inbound ID %q was kept", bad)
+		}
+	}
+}
+
+func TestRequestIDOptions(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.RequestID(kai.RequestIDOptions{
+		Header:        "X-Correlation-ID",
+		Generator:     kai.RequestIDUUIDv7,
+		IgnoreInbound: true,
+	}))
+	app.GET("/", echoRequestID)
+	client := kaitest.New(t, app)
+
+	id := client.GET("/").Header("X-Correlation-ID", "chosen-by-client").Do().Header.Get("X-Correlation-ID")
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(id) {
+		t.Errorf("ID = %q, want a fresh UUIDv7", id)
+	}
+}
+
+func TestRequestIDGenerators(t *testing.T) {
+	for name, tt := range map[string]struct {
+		gen  func() string
+		want string
+	}{
+		"uuidv4": {kai.RequestIDUUIDv4, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		"ulid":   {kai.RequestIDULID, `^[0-9A-HJKMNP-TV-Z]{26}$`},
+	} {
+		a, b := tt.gen(), tt.gen()
+		if !regexp.MustCompile(tt.want).MatchString(a) || a == b {
+			t.Errorf("%s: generated %q and %q", name, a, b)
+		}
+	}
+}
@@ -0,0 +1,617 @@
+package kai
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+// Resumable uploads follow the tus 1.0.0 protocol (https://tus.io/protocols/resumable-upload)
+// with the creation, creation-with-upload, expiration, checksum and termination extensions.
+
+const tusVersion = "1.0.0"
+
+var (
+	ErrUploadNotFound       = utils.NewHTTPError(http.StatusNotFound, "upload not found")
+	ErrUploadOffsetMismatch = utils.NewHTTPError(http.StatusConflict, "upload offset mismatch")
+	ErrUploadChunkTooLarge  = utils.NewHTTPError(http.StatusRequestEntityTooLarge, "chunk exceeds Upload-Length")
+	// ErrUploadChecksumMismatch uses 460 Checksum Mismatch from the tus checksum extension.
+	ErrUploadChecksumMismatch = utils.NewHTTPError(460, "checksum mismatch")
+)
+
+type ResumableUpload struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at,omitzero"`
+}
+
+// Complete reports whether every byte of the upload has been received.
+func (u ResumableUpload) Complete() bool {
+	return u.Offset >= u.Size
+}
+
+func (u ResumableUpload) expired(now time.Time) bool {
+	return !u.ExpiresAt.IsZero() && !u.Complete() && now.After(u.ExpiresAt)
+}
+
+// ResumableStore persists upload state and data. Implementations must be safe
+// for concurrent use and serialize writes to the same upload.
+type ResumableStore interface {
+	// Create stores a new upload and returns it with its ID assigned.
+	Create(upload ResumableUpload) (ResumableUpload, error)
+	// Get returns ErrUploadNotFound for unknown IDs.
+	Get(id string) (ResumableUpload, error)
+	// WriteChunk appends src at offset, returning ErrUploadOffsetMismatch when
+	// offset is not the current one. Bytes written before a read error are kept,
+	// unless checksum is non-nil: then the whole chunk is verified and, if it
+	// does not match, discarded before the upload is unlocked, and
+	// ErrUploadChecksumMismatch is returned. A chunk that runs past the upload's
+	// Size is discarded the same way with ErrUploadChunkTooLarge.
+	WriteChunk(id string, offset int64, src io.Reader, checksum *ChunkChecksum) (int64, error)
+	Delete(id string) error
+	List() ([]ResumableUpload, error)
+}
+
+// ChunkChecksum is the Upload-Checksum sent with a chunk. Stores feed the
+// chunk through Hash and keep it only if Valid reports true afterwards.
+type ChunkChecksum struct {
+	Hash hash.Hash
+	Sum  []byte
+}
+
+func (cs *ChunkChecksum) Valid() bool {
+	return subtle.ConstantTimeCompare(cs.Hash.Sum(nil), cs.Sum) == 1
+}
+
+type ResumableOptions struct {
+	Store ResumableStore
+	// MaxSize rejects uploads larger than this many bytes. Zero means unlimited.
+	MaxSize int64
+	// Expiration removes uploads that are not completed in time. Zero keeps them forever.
+	Expiration time.Duration
+	// OnComplete runs once per upload, after the final chunk is stored (or on
+	// creation for a zero-length upload), before the response is sent.
+	OnComplete func(c *Context, upload ResumableUpload)
+}
+
+// Resumable registers the tus endpoints under prefix: POST to create,
+// HEAD for progress, PATCH to append, DELETE to terminate and OPTIONS for discovery.
+func (a *App) Resumable(prefix string, opts ResumableOptions) {
+	if opts.Store == nil {
+		panic("Kai Resumable: Store cannot be nil")
+	}
+	h := &resumableHandler{opts: opts}
+
+	a.OPTIONS(prefix, h.options)
+	a.POST(prefix, h.requireVersion, h.create)
+	a.OPTIONS(prefix+"/:id", h.options)
+	a.HEAD(prefix+"/:id", h.requireVersion, h.head)
+	a.PATCH(prefix+"/:id", h.requireVersion, h.patch)
+	a.DELETE(prefix+"/:id", h.requireVersion, h.terminate)
+
+	if opts.Expiration > 0 {
+		go h.cleanupLoop(min(opts.Expiration, time.Hour))
+	}
+}
+
+func (g *Group) Resumable(prefix string, opts ResumableOptions) {
+	g.app.Resumable(g.Prefix+prefix, opts)
+}
+
+type resumableHandler struct {
+	opts ResumableOptions
+}
+
+func (h *resumableHandler) requireVersion(c *Context) {
+	c.SetHeader("Tus-Resumable", tusVersion)
+	if c.Header("Tus-Resumable") != tusVersion {
+		c.SetHeader("Tus-Version", tusVersion)
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, map[string]any{"error": "unsupported Tus-Resumable version"})
+		return
+	}
+	c.Next()
+}
+
+func (h *resumableHandler) options(c *Context) {
+	c.SetHeader("Tus-Resumable", tusVersion)
+	c.SetHeader("Tus-Version", tusVersion)
+	c.SetHeader("Tus-Extension", "creation,creation-with-upload,expiration,checksum,termination")
+	c.SetHeader("Tus-Checksum-Algorithm", "md5,sha1,sha256")
+	if h.opts.MaxSize > 0 {
+		c.SetHeader("Tus-Max-Size", strconv.FormatInt(h.opts.MaxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *resumableHandler) create(c *Context) {
+	if c.Header("Upload-Defer-Length") != "" {
+		c.JSON(http.StatusBadRequest, map[string]any{"error": "Upload-Defer-Length is not supported"})
+		return
+	}
+	size, err := strconv.ParseInt(c.Header("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		c.JSON(http.StatusBadRequest, map[string]any{"error": "Upload-Length must be a non-negative integer"})
+		return
+	}
+	if h.opts.MaxSize > 0 && size > h.opts.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, map[string]any{"error": "upload exceeds Tus-Max-Size"})
+		return
+	}
+	metadata, err := parseUploadMetadata(c.Header("Upload-Metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, map[string]any{"error": err.Error()})
+		return
+	}
+
+	upload := ResumableUpload{
+		Size:      size,
+		Metadata:  metadata,
+		CreatedAt: time.Now().UTC(),
+	}
+	if h.opts.Expiration > 0 {
+		upload.ExpiresAt = upload.CreatedAt.Add(h.opts.Expiration)
+	}
+	upload, err = h.opts.Store.Create(upload)
+	if err != nil {
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to create upload"})
+		return
+	}
+
+	c.SetHeader("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	h.setExpires(c, upload)
+
+	if upload.Size == 0 {
+		// Nothing to send: the upload is complete as soon as it exists.
+		if h.opts.OnComplete != nil {
+			h.opts.OnComplete(c, upload)
+		}
+		c.SetHeader("Upload-Offset", "0")
+		c.Status(http.StatusCreated)
+		return
+	}
+
+	// creation-with-upload: the POST body may carry the first chunk.
+	if c.Header("Content-Type") == "application/offset+octet-stream" {
+		upload, ok := h.writeChunk(c, upload)
+		if !ok {
+			return
+		}
+		c.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	}
+
+	c.Status(http.StatusCreated)
+}
+
+func (h *resumableHandler) head(c *Context) {
+	upload, ok := h.lookup(c, false)
+	if !ok {
+		return
+	}
+	c.SetHeader("Cache-Control", "no-store")
+	c.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.SetHeader("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	if len(upload.Metadata) > 0 {
+		c.SetHeader("Upload-Metadata", formatUploadMetadata(upload.Metadata))
+	}
+	h.setExpires(c, upload)
+	c.Status(http.StatusOK)
+}
+
+func (h *resumableHandler) patch(c *Context) {
+	if c.Header("Content-Type") != "application/offset+octet-stream" {
+		c.JSON(http.StatusUnsupportedMediaType, map[string]any{"error": "Content-Type must be application/offset+octet-stream"})
+		return
+	}
+	offset, err := strconv.ParseInt(c.Header("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, map[string]any{"error": "Upload-Offset must be a non-negative integer"})
+		return
+	}
+
+	upload, ok := h.lookup(c, true)
+	if !ok {
+		return
+	}
+	if offset != upload.Offset {
+		c.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		c.JSON(http.StatusConflict, map[string]any{"error": ErrUploadOffsetMismatch.Message})
+		return
+	}
+
+	upload, ok = h.writeChunk(c, upload)
+	if !ok {
+		return
+	}
+	c.SetHeader("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	h.setExpires(c, upload)
+	c.Status(http.StatusNoContent)
+}
+
+// writeChunk stores the request body at the upload's current offset, verifying
+// Upload-Checksum when present. It writes the error response itself on failure.
+func (h *resumableHandler) writeChunk(c *Context, upload ResumableUpload) (ResumableUpload, bool) {
+	var checksum *ChunkChecksum
+	if header := c.Header("Upload-Checksum"); header != "" {
+		algo, encoded, _ := strings.Cut(header, " ")
+		sum := newChecksumHash(algo)
+		if sum == nil {
+			c.JSON(http.StatusBadRequest, map[string]any{"error": "unsupported checksum algorithm"})
+			return upload, false
+		}
+		expected, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, map[string]any{"error": "invalid Upload-Checksum value"})
+			return upload, false
+		}
+		checksum = &ChunkChecksum{Hash: sum, Sum: expected}
+	}
+
+	start := upload.Offset
+	if c.Request.ContentLength > upload.Size-start {
+		c.JSON(http.StatusRequestEntityTooLarge, map[string]any{"error": ErrUploadChunkTooLarge.Message})
+		return upload, false
+	}
+	n, err := h.opts.Store.WriteChunk(upload.ID, start, c.Request.Body, checksum)
+	if errors.Is(err, ErrUploadOffsetMismatch) {
+		c.JSON(http.StatusConflict, map[string]any{"error": ErrUploadOffsetMismatch.Message})
+		return upload, false
+	}
+	if errors.Is(err, ErrUploadChecksumMismatch) {
+		c.JSON(ErrUploadChecksumMismatch.Code, map[string]any{"error": ErrUploadChecksumMismatch.Message})
+		return upload, false
+	}
+	if errors.Is(err, ErrUploadChunkTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, map[string]any{"error": ErrUploadChunkTooLarge.Message})
+		return upload, false
+	}
+	if err != nil {
+		// Bytes received before the failure are kept so the client can resume.
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to store chunk"})
+		return upload, false
+	}
+
+	upload.Offset = start + n
+	// Only the chunk that reaches Size completes the upload; later PATCHes
+	// at the final offset store nothing and must not fire OnComplete again.
+	if start < upload.Size && upload.Complete() && h.opts.OnComplete != nil {
+		h.opts.OnComplete(c, upload)
+	}
+	return upload, true
+}
+
+func (h *resumableHandler) terminate(c *Context) {
+	upload, ok := h.lookup(c, false)
+	if !ok {
+		return
+	}
+	if err := h.opts.Store.Delete(upload.ID); err != nil {
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to delete upload"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// lookup loads the upload named by the :id param, answering 404 or 410 itself.
+// HEAD responses must not carry a body, so errors there are status-only.
+func (h *resumableHandler) lookup(c *Context, withBody bool) (ResumableUpload, bool) {
+	fail := func(code int, message string) {
+		if withBody {
+			c.JSON(code, map[string]any{"error": message})
+		} else {
+			c.Status(code)
+		}
+	}
+
+	upload, err := h.opts.Store.Get(c.Param("id"))
+	if errors.Is(err, ErrUploadNotFound) {
+		fail(http.StatusNotFound, ErrUploadNotFound.Message)
+		return upload, false
+	}
+	if err != nil {
+		c.AddError(err)
+		fail(http.StatusInternalServerError, "failed to load upload")
+		return upload, false
+	}
+	if upload.expired(time.Now()) {
+		_ = h.opts.Store.Delete(upload.ID)
+		fail(http.StatusGone, "upload expired")
+		return upload, false
+	}
+	return upload, true
+}
+
+func (h *resumableHandler) setExpires(c *Context, upload ResumableUpload) {
+	if !upload.ExpiresAt.IsZero() && !upload.Complete() {
+		c.SetHeader("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+}
+
+func (h *resumableHandler) cleanupLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		uploads, err := h.opts.Store.List()
+		if err != nil {
+			continue
+		}
+		now := time.Now()
+		for _, upload := range uploads {
+			if upload.expired(now) {
+				_ = h.opts.Store.Delete(upload.ID)
+			}
+		}
+	}
+}
+
+func newChecksumHash(algo string) hash.Hash {
+	switch algo {
+	case "md5":
+		return md5.New()
+	case "sha1":
+		return sha1.New()
+	case "sha256":
+		return sha256.New()
+	}
+	return nil
+}
+
+// parseUploadMetadata decodes "key base64value,key2 base64value2".
+func parseUploadMetadata(header string) (map[string]string, error) {
+	if strings.TrimSpace(header) == "" {
+		return nil, nil
+	}
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		key, encoded, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, errors.New("invalid Upload-Metadata")
+		}
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.New("invalid Upload-Metadata value for " + key)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+func formatUploadMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(metadata[k])))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ---------------------------
+// Local Disk Store
+// ---------------------------
+
+// DiskUploadStore keeps each upload as "<id>.bin" plus a "<id>.json" info file.
+type DiskUploadStore struct {
+	dir   string
+	mu    sync.Mutex
+	locks map[string]*uploadLock
+}
+
+// uploadLock serializes access to one upload. It is removed from the map
+// when its last holder or waiter releases it, so lookups of unknown IDs do
+// not leave entries behind.
+type uploadLock struct {
+	sync.Mutex
+	refs int
+}
+
+func NewDiskUploadStore(dir string) (*DiskUploadStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskUploadStore{dir: dir, locks: make(map[string]*uploadLock)}, nil
+}
+
+// Path returns the data file of an upload, e.g. to move it once complete.
+func (s *DiskUploadStore) Path(id string) string {
+	return filepath.Join(s.dir, id+".bin")
+}
+
+func (s *DiskUploadStore) infoPath(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *DiskUploadStore) lock(id string) func() {
+	s.mu.Lock()
+	l, ok := s.locks[id]
+	if !ok {
+		l = &uploadLock{}
+		s.locks[id] = l
+	}
+	l.refs++
+	s.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		s.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(s.locks, id)
+		}
+		s.mu.Unlock()
+	}
+}
+
+func (s *DiskUploadStore) Create(upload ResumableUpload) (ResumableUpload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return upload, err
+	}
+	upload.ID = hex.EncodeToString(id)
+	upload.Offset = 0
+
+	f, err := os.OpenFile(s.Path(upload.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return upload, err
+	}
+	f.Close()
+	if err := s.saveInfo(upload); err != nil {
+		os.Remove(s.Path(upload.ID))
+		return upload, err
+	}
+	return upload, nil
+}
+
+func (s *DiskUploadStore) Get(id string) (ResumableUpload, error) {
+	if !validUploadID(id) {
+		return ResumableUpload{}, ErrUploadNotFound
+	}
+	unlock := s.lock(id)
+	defer unlock()
+	return s.loadInfo(id)
+}
+
+func (s *DiskUploadStore) WriteChunk(id string, offset int64, src io.Reader, checksum *ChunkChecksum) (int64, error) {
+	if !validUploadID(id) {
+		return 0, ErrUploadNotFound
+	}
+	unlock := s.lock(id)
+	defer unlock()
+
+	upload, err := s.loadInfo(id)
+	if err != nil {
+		return 0, err
+	}
+	if upload.Offset != offset {
+		return 0, ErrUploadOffsetMismatch
+	}
+
+	f, err := os.OpenFile(s.Path(id), os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	if checksum != nil {
+		src = io.TeeReader(src, checksum.Hash)
+	}
+	// Read one byte past the end so an oversized chunk is noticed, not cut short.
+	remaining := upload.Size - offset
+	n, copyErr := io.Copy(f, io.LimitReader(src, remaining+1))
+	var reject error
+	switch {
+	case n > remaining:
+		reject = ErrUploadChunkTooLarge
+	case checksum != nil && (copyErr != nil || !checksum.Valid()):
+		reject = ErrUploadChecksumMismatch
+	}
+	if reject != nil {
+		// A partial, corrupt or oversized chunk cannot be trusted; drop it
+		// while still holding the lock so no other write lands in between.
+		if err := f.Truncate(offset); err != nil {
+			return 0, err
+		}
+		return 0, reject
+	}
+	upload.Offset += n
+	if err := s.saveInfo(upload); err != nil {
+		return n, err
+	}
+	return n, copyErr
+}
+
+func (s *DiskUploadStore) Delete(id string) error {
+	if !validUploadID(id) {
+		return ErrUploadNotFound
+	}
+	unlock := s.lock(id)
+	defer unlock()
+
+	if err := os.Remove(s.Path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Remove(s.infoPath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *DiskUploadStore) List() ([]ResumableUpload, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	uploads := make([]ResumableUpload, 0, len(matches))
+	for _, m := range matches {
+		upload, err := s.Get(strings.TrimSuffix(filepath.Base(m), ".json"))
+		if err != nil {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func (s *DiskUploadStore) loadInfo(id string) (ResumableUpload, error) {
+	data, err := os.ReadFile(s.infoPath(id))
+	if os.IsNotExist(err) {
+		return ResumableUpload{}, ErrUploadNotFound
+	}
+	if err != nil {
+		return ResumableUpload{}, err
+	}
+	var upload ResumableUpload
+	err = json.Unmarshal(data, &upload)
+	return upload, err
+}
+
+// saveInfo writes the info file atomically so a crash never leaves it half-written.
+func (s *DiskUploadStore) saveInfo(upload ResumableUpload) error {
+	data, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := s.infoPath(upload.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.infoPath(upload.ID))
+}
+
+func validUploadID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package kai
+
+import (
+	"crypto/sha256"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestDiskUploadStoreReleasesLocks(t *testing.T) {
+	store, err := NewDiskUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 50 {
+		id := strings.Repeat(string("0123456789abcdef"[i%16]), 32)
+		if _, err := store.Get(id); err != ErrUploadNotFound {
+			t.Fatalf("Get(%s) = %v, want ErrUploadNotFound", id, err)
+		}
+	}
+
+	upload, err := store.Create(ResumableUpload{Size: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			store.WriteChunk(upload.ID, 0, strings.NewReader("x"), nil)
+		}()
+		go func() {
+			defer wg.Done()
+			store.Get(upload.ID)
+		}()
+	}
+	wg.Wait()
+	if err := store.Delete(upload.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if n := len(store.locks); n != 0 {
+		t.Fatalf("%d lock entries left behind", n)
+	}
+}
+
+func TestDiskUploadStoreDiscardsBadChecksum(t *testing.T) {
+	store, err := NewDiskUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload, err := store.Create(ResumableUpload{Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.WriteChunk(upload.ID, 0, strings.NewReader("ab"), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sha256.Sum256([]byte("other"))
+	n, err := store.WriteChunk(upload.ID, 2, strings.NewReader("cde"), &ChunkChecksum{Hash: sha256.New(), Sum: want[:]})
+	if n != 0 || err != ErrUploadChecksumMismatch {
+		t.Fatalf("WriteChunk = %d, %v; want 0, ErrUploadChecksumMismatch", n, err)
+	}
+	if got, _ := store.Get(upload.ID); got.Offset != 2 {
+		t.Errorf("Offset = %d after a rejected chunk, want 2", got.Offset)
+	}
+	if data, _ := os.ReadFile(store.Path(upload.ID)); string(data) != "ab" {
+		t.Errorf("data = %q, want %q", data, "ab")
+	}
+
+	want = sha256.Sum256([]byte("cde"))
+	if n, err := store.WriteChunk(upload.ID, 2, strings.NewReader("cde"), &ChunkChecksum{Hash: sha256.New(), Sum: want[:]}); n != 3 || err != nil {
+		t.Fatalf("WriteChunk = %d, %v", n, err)
+	}
+}
@@ -0,0 +1,160 @@
+package kai_test
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func newResumableApp(t *testing.T, completed *atomic.Int64) (*kai.App, *kai.DiskUploadStore) {
+	store, err := kai.NewDiskUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := kai.NewApp()
+	app.Resumable("/uploads", kai.ResumableOptions{
+		Store:   store,
+		MaxSize: 1 << 20,
+		OnComplete: func(c *kai.Context, u kai.ResumableUpload) {
+			completed.Add(1)
+		},
+	})
+	return app, store
+}
+
+func tusClient(t *testing.T, app *kai.App) *kaitest.Client {
+	return kaitest.New(t, app).SetHeader("Tus-Resumable", "1.0.0")
+}
+
+func tusPatch(client *kaitest.Client, location, offset, chunk string) *kaitest.Response {
+	return client.PATCH(location).
+		Header("Upload-Offset", offset).
+		Body("application/offset+octet-stream", strings.NewReader(chunk)).
+		Do()
+}
+
+func TestResumableUpload(t *testing.T) {
+	var completed atomic.Int64
+	app, store := newResumableApp(t, &completed)
+	client := tusClient(t, app)
+
+	created := client.POST("/uploads").
+		Header("Upload-Length", "11").
+		Header("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("hello.txt"))).
+		Do().ExpectStatus(http.StatusCreated)
+	location := created.Header.Get("Location")
+	if !strings.HasPrefix(location, "/uploads/") {
+		t.Fatalf("Location = %q", location)
+	}
+
+	tusPatch(client, location, "0", "hello ").ExpectStatus(http.StatusNoContent).ExpectHeader("Upload-Offset", "6")
+	tusPatch(client, location, "0", "again").ExpectStatus(http.StatusConflict).ExpectHeader("Upload-Offset", "6")
+	client.HEAD(location).Do().
+		ExpectStatus(http.StatusOK).
+		ExpectHeader("Upload-Offset", "6").
+		ExpectHeader("Upload-Length", "11")
+	if completed.Load() != 0 {
+		t.Fatal("OnComplete fired before the upload finished")
+	}
+
+	tusPatch(client, location, "6", "world").ExpectStatus(http.StatusNoContent).ExpectHeader("Upload-Offset", "11")
+	// Repeating the final offset stores nothing and must not complete again.
+	tusPatch(client, location, "11", "").ExpectStatus(http.StatusNoContent)
+	tusPatch(client, location, "11", "extra").ExpectStatus(http.StatusRequestEntityTooLarge)
+	if n := completed.Load(); n != 1 {
+		t.Fatalf("OnComplete fired %d times, want 1", n)
+	}
+
+	id := strings.TrimPrefix(location, "/uploads/")
+	if u, err := store.Get(id); err != nil || u.Metadata["filename"] != "hello.txt" {
+		t.Fatalf("Get = %+v, %v", u, err)
+	}
+	client.DELETE(location).Do().ExpectStatus(http.StatusNoContent)
+	client.HEAD(location).Do().ExpectStatus(http.StatusNotFound)
+}
+
+func TestResumableZeroLength(t *testing.T) {
+	var completed atomic.Int64
+	app, _ := newResumableApp(t, &completed)
+	client := tusClient(t, app)
+
+	location := client.POST("/uploads").Header("Upload-Length", "0").Do().
+		ExpectStatus(http.StatusCreated).Header.Get("Location")
+	tusPatch(client, location, "0", "").ExpectStatus(http.StatusNoContent)
+	if n := completed.Load(); n != 1 {
+		t.Fatalf("OnComplete fired %d times, want 1", n)
+	}
+}
+
+func TestResumableChecksum(t *testing.T) {
+	var completed atomic.Int64
+	app, _ := newResumableApp(t, &completed)
+	client := tusClient(t, app)
+
+	location := client.POST("/uploads").Header("Upload-Length", "5").Do().Header.Get("Location")
+	wrong := sha256.Sum256([]byte("other"))
+	client.PATCH(location).
+		Header("Upload-Offset", "0").
+		Header("Upload-Checksum", "sha256 "+base64.StdEncoding.EncodeToString(wrong[:])).
+		Body("application/offset+octet-stream", strings.NewReader("hello")).
+		Do().ExpectStatus(460)
+	client.HEAD(location).Do().ExpectHeader("Upload-Offset", "0")
+
+	right := sha256.Sum256([]byte("hello"))
+	client.PATCH(location).
+		Header("Upload-Offset", "0").
+		Header("Upload-Checksum", "sha256 "+base64.StdEncoding.EncodeToString(right[:])).
+		Body("application/offset+octet-stream", strings.NewReader("hello")).
+		Do().ExpectStatus(http.StatusNoContent)
+	if n := completed.Load(); n != 1 {
+		t.Fatalf("OnComplete fired %d times, want 1", n)
+	}
+}
+
+func TestResumableRejectsOversizedChunk(t *testing.T) {
+	var completed atomic.Int64
+	app, store := newResumableApp(t, &completed)
+	client := tusClient(t, app)
+
+	location := client.POST("/uploads").Header("Upload-Length", "5").Do().Header.Get("Location")
+	tusPatch(client, location, "0", "hel").ExpectStatus(http.StatusNoContent)
+	tusPatch(client, location, "3", "lo, world").
+		ExpectStatus(http.StatusRequestEntityTooLarge).
+		ExpectJSON(map[string]any{"error": "chunk exceeds Upload-Length"})
+
+	// Without a Content-Length the store notices the extra bytes itself.
+	client.PATCH(location).
+		Header("Upload-Offset", "3").
+		Body("application/offset+octet-stream", io.MultiReader(strings.NewReader("lo"), strings.NewReader("!"))).
+		Do().ExpectStatus(http.StatusRequestEntityTooLarge)
+
+	client.HEAD(location).Do().ExpectHeader("Upload-Offset", "3")
+	if completed.Load() != 0 {
+		t.Fatal("OnComplete fired for a rejected chunk")
+	}
+	tusPatch(client, location, "3", "lo").ExpectStatus(http.StatusNoContent).ExpectHeader("Upload-Offset", "5")
+	id := location[strings.LastIndex(location, "/")+1:]
+	if data, _ := os.ReadFile(store.Path(id)); string(data) != "hello" {
+		t.Errorf("data = %q, want %q", data, "hello")
+	}
+}
+
+func TestResumableProtocolErrors(t *testing.T) {
+	var completed atomic.Int64
+	app, _ := newResumableApp(t, &completed)
+	client := tusClient(t, app)
+
+	kaitest.New(t, app).POST("/uploads").Header("Upload-Length", "1").Do().ExpectStatus(http.StatusPreconditionFailed)
+	client.POST("/uploads").Header("Upload-Length", "-1").Do().ExpectStatus(http.StatusBadRequest)
+	client.POST("/uploads").Header("Upload-Length", "2000000").Do().ExpectStatus(http.StatusRequestEntityTooLarge)
+	client.HEAD("/uploads/" + strings.Repeat("0", 32)).Do().ExpectStatus(http.StatusNotFound)
+	client.OPTIONS("/uploads").Do().ExpectStatus(http.StatusNoContent).ExpectHeader("Tus-Max-Size", "1048576")
+}
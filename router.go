@@ -1,35 +1,64 @@
 package kai
 
 import (
+	"errors"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/dipto-kainin/kai/utils"
 )
 
 type Router struct {
-	routes           map[string][]routeEntry // method => list of routes
+	routes           map[string][]*routeEntry // method => list of routes
+	names            map[string]*routeEntry   // route name => route, for URL building
 	globalMiddleware []HandlerFunc
 	NotFoundHandler  HandlerFunc
+
+	// ErrorHandler renders errors passed to Context.AbortWithError.
+	ErrorHandler func(c *Context, err error)
+
+	// MaxMultipartMemory is the memory budget for ParseMultipartForm; larger
+	// files spill to temporary files. Defaults to 32 MiB.
+	MaxMultipartMemory int64
+
+	htmlRender *htmlRenderer
+	cookies    *CookieCodec
 }
 
 type routeEntry struct {
+	name     string
 	pattern  string
 	segments []segment
 	handlers []HandlerFunc
 }
 
 type segment struct {
-	literal   string
-	isParam   bool
-	paramName string
+	literal    string
+	isParam    bool
+	isWildcard bool // "*name" captures the rest of the path, must be last
+	paramName  string
+	constraint *regexp.Regexp // from ":name<int>" or ":name<[a-z-]+>", nil if unconstrained
+}
+
+// paramConstraints are the named constraints usable as ":id<int>".
+// Anything else between the angle brackets is compiled as a regular expression.
+var paramConstraints = map[string]string{
+	"int":   `-?[0-9]+`,
+	"uint":  `[0-9]+`,
+	"uuid":  `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+	"alpha": `[a-zA-Z]+`,
+	"alnum": `[a-zA-Z0-9]+`,
 }
 
 func NewRouter() *Router {
 	r := &Router{}
-	r.routes = make(map[string][]routeEntry)
+	r.routes = make(map[string][]*routeEntry)
+	r.names = make(map[string]*routeEntry)
 	r.globalMiddleware = []HandlerFunc{}
 	r.NotFoundHandler = default404Handler
+	r.ErrorHandler = DefaultErrorHandler
+	r.MaxMultipartMemory = defaultMultipartMemory
 	return r
 }
 
@@ -39,6 +68,27 @@ func default404Handler(c *Context) {
 	})
 }
 
+// DefaultErrorHandler responds with the code, message and headers of a
+// *utils.HTTPError, and with a generic 500 for any other error.
+func DefaultErrorHandler(c *Context, err error) {
+	if c.wroteHeader {
+		return
+	}
+	var httpErr *utils.HTTPError
+	if errors.As(err, &httpErr) {
+		for key, values := range httpErr.Header {
+			c.Writer.Header()[key] = values
+		}
+		c.JSON(httpErr.Code, map[string]any{
+			"error": httpErr.Message,
+		})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, map[string]any{
+		"error": "Internal Server Error",
+	})
+}
+
 // ---------------------------
 // Middleware Registration
 // ---------------------------
@@ -51,32 +101,61 @@ func (r *Router) Use(mw ...HandlerFunc) {
 // Route Registration
 // ---------------------------
 
-func (r *Router) GET(pattern string, handlers ...HandlerFunc) {
-	r.addRoute("GET", pattern, handlers)
+func (r *Router) GET(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("GET", pattern, handlers)
 }
 
-func (r *Router) POST(pattern string, handlers ...HandlerFunc) {
-	r.addRoute("POST", pattern, handlers)
+func (r *Router) POST(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("POST", pattern, handlers)
 }
 
-func (r *Router) PUT(pattern string, handlers ...HandlerFunc) {
-	r.addRoute("PUT", pattern, handlers)
+func (r *Router) PUT(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("PUT", pattern, handlers)
 }
 
-func (r *Router) DELETE(pattern string, handlers ...HandlerFunc) {
-	r.addRoute("DELETE", pattern, handlers)
+func (r *Router) DELETE(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("DELETE", pattern, handlers)
 }
 
-func (r *Router) addRoute(method string, pattern string, handlers []HandlerFunc) {
+func (r *Router) HEAD(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("HEAD", pattern, handlers)
+}
+
+func (r *Router) PATCH(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("PATCH", pattern, handlers)
+}
+
+func (r *Router) OPTIONS(pattern string, handlers ...HandlerFunc) *Route {
+	return r.addRoute("OPTIONS", pattern, handlers)
+}
+
+func (r *Router) addRoute(method string, pattern string, handlers []HandlerFunc) *Route {
 	segments := parsePattern(pattern)
 
-	entry := routeEntry{
+	entry := &routeEntry{
 		pattern:  pattern,
 		segments: segments,
 		handlers: handlers,
 	}
 
 	r.routes[method] = append(r.routes[method], entry)
+	return &Route{router: r, entry: entry}
+}
+
+// Route is returned by route registration so the route can be named.
+type Route struct {
+	router *Router
+	entry  *routeEntry
+}
+
+// Name registers the route under name for URL building. Names must be unique.
+func (rt *Route) Name(name string) *Route {
+	if _, exists := rt.router.names[name]; exists {
+		panic("Kai router: duplicate route name " + name)
+	}
+	rt.entry.name = name
+	rt.router.names[name] = rt.entry
+	return rt
 }
 
 // ---------------------------
@@ -88,7 +167,7 @@ func parsePattern(pattern string) []segment {
 
 	parts := strings.Split(pattern, "/")
 
-	for _, part := range parts {
+	for i, part := range parts {
 		if part == "" {
 			continue
 		}
@@ -98,6 +177,19 @@ func parsePattern(pattern string) []segment {
 		if part[0] == ':' {
 			seg.isParam = true
 			seg.paramName = part[1:]
+			if open := strings.IndexByte(part, '<'); open > 0 && strings.HasSuffix(part, ">") {
+				seg.paramName = part[1:open]
+				seg.constraint = compileConstraint(pattern, part[open+1:len(part)-1])
+			}
+		} else if part[0] == '*' {
+			if i != len(parts)-1 {
+				panic("Kai router: wildcard segment must be last in pattern " + pattern)
+			}
+			seg.isWildcard = true
+			seg.paramName = part[1:]
+			if seg.paramName == "" {
+				seg.paramName = "filepath"
+			}
 		} else {
 			seg.literal = part
 			seg.isParam = false
@@ -109,43 +201,85 @@ func parsePattern(pattern string) []segment {
 	return segments
 }
 
+func compileConstraint(pattern, expr string) *regexp.Regexp {
+	if named, ok := paramConstraints[expr]; ok {
+		expr = named
+	}
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		panic("Kai router: invalid param constraint in pattern " + pattern + ": " + err.Error())
+	}
+	return re
+}
+
 // ---------------------------
 // Route Matching
 // ---------------------------
 
-func (r *Router) findRoute(method string, path string) (routeEntry, map[string]string, bool) {
+// findRoute tries literal and param routes in registration order first, and
+// only then wildcard routes, longest prefix first, so a catch-all such as
+// "/*filepath" never shadows a more specific route registered after it.
+func (r *Router) findRoute(method string, path string) (*routeEntry, map[string]string, bool) {
 	reqSegments := utils.SplitPath(path)
 
 	entries, ok := r.routes[method]
 	if !ok {
-		return routeEntry{}, nil, false
+		return nil, nil, false
 	}
 
+	var wildcard *routeEntry
+	var wildcardParams map[string]string
 	for _, entry := range entries {
-		if len(entry.segments) != len(reqSegments) {
+		n := len(entry.segments)
+		if n > 0 && entry.segments[n-1].isWildcard {
+			if wildcard != nil && len(wildcard.segments) >= n {
+				continue
+			}
+			if params, ok := matchSegments(entry.segments, reqSegments); ok {
+				wildcard, wildcardParams = entry, params
+			}
 			continue
 		}
+		if params, ok := matchSegments(entry.segments, reqSegments); ok {
+			return entry, params, true
+		}
+	}
 
-		params := make(map[string]string)
-		matched := true
+	if wildcard != nil {
+		return wildcard, wildcardParams, true
+	}
+	return nil, nil, false
+}
 
-		for i, seg := range entry.segments {
-			reqSeg := reqSegments[i]
+func matchSegments(segments []segment, reqSegments []string) (map[string]string, bool) {
+	n := len(segments)
+	if n > 0 && segments[n-1].isWildcard {
+		if len(reqSegments) < n-1 {
+			return nil, false
+		}
+	} else if n != len(reqSegments) {
+		return nil, false
+	}
 
-			if seg.isParam {
-				params[seg.paramName] = reqSeg
-			} else if seg.literal != reqSeg {
-				matched = false
-				break
-			}
+	params := make(map[string]string)
+	for i, seg := range segments {
+		if seg.isWildcard {
+			params[seg.paramName] = strings.Join(reqSegments[i:], "/")
+			break
 		}
 
-		if matched {
-			return entry, params, true
+		reqSeg := reqSegments[i]
+
+		if seg.isParam {
+			if seg.constraint != nil && !seg.constraint.MatchString(reqSeg) {
+				return nil, false
+			}
+			params[seg.paramName] = reqSeg
+		} else if seg.literal != reqSeg {
+			return nil, false
 		}
 	}
-
-	return routeEntry{}, nil, false
+	return params, true
 }
 
 // ---------------------------
@@ -153,13 +287,13 @@ func (r *Router) findRoute(method string, path string) (routeEntry, map[string]s
 // ---------------------------
 
 func (r *Router) HandlerHTTP(w http.ResponseWriter, req *http.Request) {
-	ctx := NewContext(w, req)
+	ctx := r.NewContext(w, req)
 
 	entry, params, found := r.findRoute(req.Method, req.URL.Path)
 
 	if !found {
 		// Run dynamic 404 handler
-		ctx.Handlers = append(r.globalMiddleware, r.NotFoundHandler)
+		ctx.Handlers = r.handlerChain([]HandlerFunc{r.NotFoundHandler})
 		ctx.Next()
 		return
 	}
@@ -170,11 +304,27 @@ func (r *Router) HandlerHTTP(w http.ResponseWriter, req *http.Request) {
 	ctx.Route = entry.pattern
 
 	// merge global + route handlers
-	ctx.Handlers = append(r.globalMiddleware, entry.handlers...)
+	ctx.Handlers = r.handlerChain(entry.handlers)
 
 	ctx.Next()
 }
 
+// handlerChain returns global middleware followed by handlers in a fresh
+// slice, so concurrent requests never share a backing array.
+func (r *Router) handlerChain(handlers []HandlerFunc) []HandlerFunc {
+	chain := make([]HandlerFunc, 0, len(r.globalMiddleware)+len(handlers))
+	chain = append(chain, r.globalMiddleware...)
+	return append(chain, handlers...)
+}
+
+// NewContext creates a Context bound to this router, so helpers that depend
+// on router configuration (HTML templates, multipart limits) work.
+func (r *Router) NewContext(w http.ResponseWriter, req *http.Request) *Context {
+	ctx := NewContext(w, req)
+	ctx.router = r
+	return ctx
+}
+
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.HandlerHTTP(w, req)
 }
@@ -0,0 +1,38 @@
+package kai_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestRouterParamsAndNotFound(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/users/:id", func(c *kai.Context) { c.String(http.StatusOK, "user "+c.Param("id")) })
+	app.GET("/users/:id/posts/:post", func(c *kai.Context) {
+		c.String(http.StatusOK, c.Param("id")+"/"+c.Param("post"))
+	})
+	client := kaitest.New(t, app)
+
+	client.GET("/users/42").Do().ExpectStatus(http.StatusOK).ExpectBody("user 42")
+	client.GET("/users/42/posts/7").Do().ExpectBody("42/7")
+	client.GET("/users").Do().ExpectStatus(http.StatusNotFound).ExpectJSON(map[string]any{"error": "route not found"})
+	client.POST("/users/42").Do().ExpectStatus(http.StatusNotFound)
+}
+
+func TestRouterWildcardsMatchLast(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/*filepath", func(c *kai.Context) { c.String(http.StatusOK, "root "+c.Param("filepath")) })
+	app.GET("/files/*rest", func(c *kai.Context) { c.String(http.StatusOK, "files "+c.Param("rest")) })
+	app.GET("/api/ping", func(c *kai.Context) { c.String(http.StatusOK, "pong") })
+	app.GET("/api/:name", func(c *kai.Context) { c.String(http.StatusOK, "api "+c.Param("name")) })
+	client := kaitest.New(t, app)
+
+	client.GET("/api/ping").Do().ExpectBody("pong")
+	client.GET("/api/other").Do().ExpectBody("api other")
+	client.GET("/files/a/b.txt").Do().ExpectBody("files a/b.txt")
+	client.GET("/files").Do().ExpectBody("files ")
+	client.GET("/app/settings").Do().ExpectBody("root app/settings")
+}
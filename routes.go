@@ -0,0 +1,194 @@
+package kai
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// RouteInfo describes one registered route.
+type RouteInfo struct {
+	Method  string `json:"method"`
+	Pattern string `json:"pattern"`
+	Name    string `json:"name,omitempty"`
+	// Handler is the last function in the chain, the one that serves the request.
+	Handler string `json:"handler"`
+	// Middleware lists global middleware followed by route-level middleware, in run order.
+	Middleware []string `json:"middleware"`
+}
+
+var methodOrder = map[string]int{
+	"GET": 0, "HEAD": 1, "POST": 2, "PUT": 3, "PATCH": 4, "DELETE": 5, "OPTIONS": 6,
+}
+
+// Routes returns every registered route sorted by pattern, then method.
+func (r *Router) Routes() []RouteInfo {
+	var routes []RouteInfo
+	for method, entries := range r.routes {
+		for _, entry := range entries {
+			chain := r.handlerChain(entry.handlers)
+			info := RouteInfo{
+				Method:     method,
+				Pattern:    entry.pattern,
+				Name:       entry.name,
+				Middleware: []string{},
+			}
+			if len(chain) > 0 {
+				info.Handler = handlerName(chain[len(chain)-1])
+				for _, h := range chain[:len(chain)-1] {
+					info.Middleware = append(info.Middleware, handlerName(h))
+				}
+			}
+			routes = append(routes, info)
+		}
+	}
+
+	sort.SliceStable(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return methodRank(routes[i].Method) < methodRank(routes[j].Method)
+	})
+	return routes
+}
+
+func methodRank(method string) int {
+	if rank, ok := methodOrder[method]; ok {
+		return rank
+	}
+	return len(methodOrder)
+}
+
+// handlerName returns a readable function name such as "kai.Logger.func1".
+func handlerName(h HandlerFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+func (a *App) Routes() []RouteInfo {
+	return a.Router.Routes()
+}
+
+// PrintRoutes writes the route table to w.
+func (a *App) PrintRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATTERN\tHANDLER\tMIDDLEWARE")
+	for _, route := range a.Routes() {
+		middleware := strings.Join(route.Middleware, " -> ")
+		if middleware == "" {
+			middleware = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", route.Method, route.Pattern, route.Handler, middleware)
+	}
+	tw.Flush()
+}
+
+// RoutesEndpoint registers a GET route that serves the route table as JSON.
+// It is opt-in; pass middleware to protect it in production.
+func (a *App) RoutesEndpoint(path string, middleware ...HandlerFunc) {
+	handlers := append(append([]HandlerFunc{}, middleware...), func(c *Context) {
+		c.JSON(http.StatusOK, map[string]any{"routes": a.Routes()})
+	})
+	a.GET(path, handlers...)
+}
+
+// ---------------------------
+// Reverse URL Building
+// ---------------------------
+
+// URL builds the path of the route registered under name. pairs alternate
+// keys and values: keys matching path params fill the pattern (escaped),
+// the rest become the query string. It panics on an unknown name or a
+// missing param so broken links fail loudly.
+//
+//	app.URL("post.file", "id", 5)             // "/api/posts/5/file"
+//	app.URL("posts.list", "page", 2, "q", "a") // "/api/posts?page=2&q=a"
+func (r *Router) URL(name string, pairs ...any) string {
+	entry, ok := r.names[name]
+	if !ok {
+		panic(fmt.Sprintf("Kai URL: no route named %q", name))
+	}
+	if len(pairs)%2 != 0 {
+		panic(fmt.Sprintf("Kai URL: odd number of key/value arguments for route %q", name))
+	}
+
+	values := make(map[string]string, len(pairs)/2)
+	var order []string
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			panic(fmt.Sprintf("Kai URL: key %v for route %q is not a string", pairs[i], name))
+		}
+		if _, seen := values[key]; !seen {
+			order = append(order, key)
+		}
+		values[key] = fmt.Sprint(pairs[i+1])
+	}
+
+	var b strings.Builder
+	used := make(map[string]bool)
+	for _, seg := range entry.segments {
+		b.WriteByte('/')
+		switch {
+		case seg.isWildcard:
+			used[seg.paramName] = true
+			parts := strings.Split(strings.Trim(values[seg.paramName], "/"), "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			b.WriteString(strings.Join(parts, "/"))
+		case seg.isParam:
+			value, ok := values[seg.paramName]
+			if !ok || value == "" {
+				panic(fmt.Sprintf("Kai URL: route %q requires param %q", name, seg.paramName))
+			}
+			if seg.constraint != nil && !seg.constraint.MatchString(value) {
+				panic(fmt.Sprintf("Kai URL: param %q = %q does not match the constraint of route %q", seg.paramName, value, name))
+			}
+			used[seg.paramName] = true
+			b.WriteString(url.PathEscape(value))
+		default:
+			b.WriteString(seg.literal)
+		}
+	}
+	path := b.String()
+	if path == "" {
+		path = "/"
+	}
+
+	query := url.Values{}
+	for _, key := range order {
+		if !used[key] {
+			query.Add(key, values[key])
+		}
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return path
+}
+
+func (a *App) URL(name string, pairs ...any) string {
+	return a.Router.URL(name, pairs...)
+}
+
+// URLFor builds a URL for a named route of the router serving this request.
+func (c *Context) URLFor(name string, pairs ...any) string {
+	if c.router == nil {
+		panic("Kai URL: context is not bound to a router")
+	}
+	return c.router.URL(name, pairs...)
+}
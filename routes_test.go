@@ -0,0 +1,123 @@
+package kai_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func listPosts(c *kai.Context) { c.String(http.StatusOK, "posts") }
+
+func requireAdmin(c *kai.Context) { c.Next() }
+
+func TestRoutesIntrospection(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.DamageControl())
+	api := app.Group("/api")
+	api.Use(requireAdmin)
+	api.POST("/posts", listPosts)
+	api.GET("/posts", listPosts).Name("posts.list")
+
+	routes := app.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("Routes() = %+v", routes)
+	}
+	get, post := routes[0], routes[1]
+	if get.Method != "GET" || post.Method != "POST" || get.Pattern != "/api/posts" {
+		t.Fatalf("routes not sorted by pattern then method: %+v", routes)
+	}
+	if get.Name != "posts.list" || post.Name != "" {
+		t.Errorf("names = %q, %q", get.Name, post.Name)
+	}
+	if get.Handler != "kai_test.listPosts" {
+		t.Errorf("Handler = %q", get.Handler)
+	}
+	if len(get.Middleware) != 2 || !strings.HasPrefix(get.Middleware[0], "kai.DamageControl") || get.Middleware[1] != "kai_test.requireAdmin" {
+		t.Errorf("Middleware = %v", get.Middleware)
+	}
+
+	var out strings.Builder
+	app.PrintRoutes(&out)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "METHOD") || !strings.Contains(lines[1], "kai_test.listPosts") {
+		t.Errorf("PrintRoutes:\n%s", out.String())
+	}
+}
+
+func TestRoutesEndpoint(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/posts", listPosts).Name("posts.list")
+	app.RoutesEndpoint("/_routes", func(c *kai.Context) {
+		if c.Header("X-Admin") != "yes" {
+			c.AbortWithStatusJSON(http.StatusForbidden, map[string]string{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	})
+	client := kaitest.New(t, app)
+
+	client.GET("/_routes").Do().ExpectStatus(http.StatusForbidden)
+	client.GET("/_routes").Header("X-Admin", "yes").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectJSONPath("routes.0.pattern", "/_routes").
+		ExpectJSONPath("routes.1.pattern", "/posts").
+		ExpectJSONPath("routes.1.name", "posts.list").
+		ExpectJSONPath("routes.1.handler", "kai_test.listPosts")
+}
+
+func TestNamedRouteURLs(t *testing.T) {
+	app := kai.NewApp()
+	api := app.Group("/api")
+	api.GET("/posts/:id/file", func(c *kai.Context) {
+		c.String(http.StatusOK, c.URLFor("post.file", "id", c.Param("id"), "dl", true))
+	}).Name("post.file")
+	api.GET("/users/:id<int>", listPosts).Name("user.show")
+	app.GET("/static/*filepath", listPosts).Name("static")
+	app.GET("/", listPosts).Name("home")
+
+	tests := []struct {
+		name  string
+		pairs []any
+		want  string
+	}{
+		{"post.file", []any{"id", 5}, "/api/posts/5/file"},
+		{"post.file", []any{"id", "a b/c"}, "/api/posts/a%20b%2Fc/file"},
+		{"post.file", []any{"id", 5, "page", 2, "q", "x&y"}, "/api/posts/5/file?page=2&q=x%26y"},
+		{"user.show", []any{"id", 7}, "/api/users/7"},
+		{"static", []any{"filepath", "css/site main.css"}, "/static/css/site%20main.css"},
+		{"home", nil, "/"},
+	}
+	for _, tt := range tests {
+		if got := app.URL(tt.name, tt.pairs...); got != tt.want {
+			t.Errorf("URL(%q, %v) = %q, want %q", tt.name, tt.pairs, got, tt.want)
+		}
+	}
+
+	kaitest.New(t, app).GET("/api/posts/9/file").Do().ExpectBody("/api/posts/9/file?dl=true")
+}
+
+func TestNamedRouteURLPanics(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/users/:id<int>", listPosts).Name("user.show")
+
+	for name, build := range map[string]func(){
+		"unknown name":   func() { app.URL("missing") },
+		"missing param":  func() { app.URL("user.show") },
+		"odd pairs":      func() { app.URL("user.show", "id") },
+		"constraint":     func() { app.URL("user.show", "id", "abc") },
+		"duplicate name": func() { app.GET("/other", listPosts).Name("user.show") },
+		"non-string key": func() { app.URL("user.show", 1, 2) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected a panic")
+				}
+			}()
+			build()
+		})
+	}
+}
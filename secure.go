@@ -0,0 +1,245 @@
+package kai
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SecureHeadersOptions sets each security header; an empty field is not
+// sent. Start from DefaultSecureHeaders and change what you need.
+type SecureHeadersOptions struct {
+	ContentTypeOptions string
+	FrameOptions       string
+	XSSProtection      string
+	ReferrerPolicy     string
+	PermissionsPolicy  string
+
+	// ContentSecurityPolicy may contain "{nonce}", replaced per request with a
+	// fresh nonce that handlers read with c.CSPNonce, e.g.
+	// "script-src 'self' 'nonce-{nonce}'".
+	ContentSecurityPolicy string
+	// CSPReportOnly sends the policy as Content-Security-Policy-Report-Only,
+	// to try a policy without enforcing it.
+	CSPReportOnly bool
+	// CSPReportURI is appended as a report-uri directive, e.g. the path
+	// served by CSPReportHandler.
+	CSPReportURI string
+
+	// HSTSMaxAge enables Strict-Transport-Security on HTTPS requests only;
+	// browsers ignore it over plain HTTP.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// HSTSPreload asks for inclusion in browser preload lists, which is hard
+	// to undo; only enable it deliberately.
+	HSTSPreload bool
+	// TrustForwardedProto treats X-Forwarded-Proto: https as HTTPS, for apps
+	// behind a TLS-terminating proxy.
+	TrustForwardedProto bool
+}
+
+// DefaultSecureHeaders returns the options SecureHeaders uses when called
+// without any.
+func DefaultSecureHeaders() SecureHeadersOptions {
+	return SecureHeadersOptions{
+		ContentTypeOptions:    "nosniff",
+		FrameOptions:          "DENY",
+		XSSProtection:         "1; mode=block",
+		ReferrerPolicy:        "no-referrer",
+		PermissionsPolicy:     "geolocation=(), microphone=()",
+		ContentSecurityPolicy: "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; img-src 'self' data: https:; font-src 'self'; connect-src 'self'; frame-ancestors 'none'",
+		HSTSMaxAge:            2 * 365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+	}
+}
+
+const cspNonceKey = "kai.cspNonce"
+
+// SecureHeaders sets common security headers on every response.
+func SecureHeaders(opts ...SecureHeadersOptions) HandlerFunc {
+	o := DefaultSecureHeaders()
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+
+	csp := o.ContentSecurityPolicy
+	if csp != "" && o.CSPReportURI != "" && !strings.Contains(csp, "report-uri") {
+		csp = strings.TrimRight(strings.TrimSpace(csp), ";") + "; report-uri " + o.CSPReportURI
+	}
+	cspHeader := "Content-Security-Policy"
+	if o.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	useNonce := strings.Contains(csp, "{nonce}")
+
+	hsts := ""
+	if o.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.FormatInt(int64(o.HSTSMaxAge.Seconds()), 10)
+		if o.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if o.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	static := map[string]string{
+		"X-Content-Type-Options": o.ContentTypeOptions,
+		"X-Frame-Options":        o.FrameOptions,
+		"X-XSS-Protection":       o.XSSProtection,
+		"Referrer-Policy":        o.ReferrerPolicy,
+		"Permissions-Policy":     o.PermissionsPolicy,
+	}
+
+	return func(c *Context) {
+		h := c.Writer.Header()
+		for name, value := range static {
+			if value != "" {
+				h.Set(name, value)
+			}
+		}
+		if hsts != "" && isHTTPS(c.Request, o.TrustForwardedProto) {
+			h.Set("Strict-Transport-Security", hsts)
+		}
+		if csp != "" {
+			policy := csp
+			if useNonce {
+				nonce := newCSPNonce()
+				c.Set(cspNonceKey, nonce)
+				policy = strings.ReplaceAll(csp, "{nonce}", nonce)
+			}
+			h.Set(cspHeader, policy)
+		}
+		c.Next()
+	}
+}
+
+func isHTTPS(r *http.Request, trustProxy bool) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return trustProxy && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+func newCSPNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// CSPNonce returns this request's CSP nonce for inline <script nonce="...">
+// and <style nonce="..."> tags, or "" when the policy has no "{nonce}".
+func (c *Context) CSPNonce() string {
+	nonce, _ := c.Get(cspNonceKey)
+	s, _ := nonce.(string)
+	return s
+}
+
+// ---------------------------
+// CSP Violation Reports
+// ---------------------------
+
+// CSPReport is one policy violation sent by a browser, in either the legacy
+// report-uri format or the Reporting API format.
+type CSPReport struct {
+	DocumentURI        string `json:"document_uri"`
+	Referrer           string `json:"referrer,omitempty"`
+	BlockedURI         string `json:"blocked_uri"`
+	EffectiveDirective string `json:"effective_directive"`
+	OriginalPolicy     string `json:"original_policy"`
+	Disposition        string `json:"disposition,omitempty"`
+	SourceFile         string `json:"source_file,omitempty"`
+	LineNumber         int    `json:"line_number,omitempty"`
+	ColumnNumber       int    `json:"column_number,omitempty"`
+	StatusCode         int    `json:"status_code,omitempty"`
+}
+
+// CSPReportHandler receives violation reports, typically at the
+// CSPReportURI path, and passes each to onReport. With a nil onReport the
+// reports are logged. It always answers 204.
+func CSPReportHandler(onReport func(c *Context, report CSPReport)) HandlerFunc {
+	if onReport == nil {
+		onReport = func(c *Context, r CSPReport) {
+			log.Printf("CSP violation: %s blocked %q on %s", r.EffectiveDirective, r.BlockedURI, r.DocumentURI)
+		}
+	}
+	return func(c *Context) {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, 64<<10))
+		if err == nil {
+			for _, report := range parseCSPReports(body) {
+				onReport(c, report)
+			}
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func parseCSPReports(body []byte) []CSPReport {
+	// Legacy: {"csp-report": {"document-uri": ...}}
+	var legacy struct {
+		Report *struct {
+			DocumentURI        string `json:"document-uri"`
+			Referrer           string `json:"referrer"`
+			BlockedURI         string `json:"blocked-uri"`
+			ViolatedDirective  string `json:"violated-directive"`
+			EffectiveDirective string `json:"effective-directive"`
+			OriginalPolicy     string `json:"original-policy"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"source-file"`
+			LineNumber         int    `json:"line-number"`
+			ColumnNumber       int    `json:"column-number"`
+			StatusCode         int    `json:"status-code"`
+		} `json:"csp-report"`
+	}
+	if json.Unmarshal(body, &legacy) == nil && legacy.Report != nil {
+		r := legacy.Report
+		directive := r.EffectiveDirective
+		if directive == "" {
+			directive = r.ViolatedDirective
+		}
+		return []CSPReport{{
+			DocumentURI: r.DocumentURI, Referrer: r.Referrer, BlockedURI: r.BlockedURI,
+			EffectiveDirective: directive, OriginalPolicy: r.OriginalPolicy, Disposition: r.Disposition,
+			SourceFile: r.SourceFile, LineNumber: r.LineNumber, ColumnNumber: r.ColumnNumber, StatusCode: r.StatusCode,
+		}}
+	}
+
+	// Reporting API: [{"type": "csp-violation", "body": {"documentURL": ...}}]
+	var batch []struct {
+		Type string `json:"type"`
+		Body struct {
+			DocumentURL        string `json:"documentURL"`
+			Referrer           string `json:"referrer"`
+			BlockedURL         string `json:"blockedURL"`
+			EffectiveDirective string `json:"effectiveDirective"`
+			OriginalPolicy     string `json:"originalPolicy"`
+			Disposition        string `json:"disposition"`
+			SourceFile         string `json:"sourceFile"`
+			LineNumber         int    `json:"lineNumber"`
+			ColumnNumber       int    `json:"columnNumber"`
+			StatusCode         int    `json:"statusCode"`
+		} `json:"body"`
+	}
+	if json.Unmarshal(body, &batch) != nil {
+		return nil
+	}
+	reports := make([]CSPReport, 0, len(batch))
+	for _, item := range batch {
+		if item.Type != "csp-violation" {
+			continue
+		}
+		b := item.Body
+		reports = append(reports, CSPReport{
+			DocumentURI: b.DocumentURL, Referrer: b.Referrer, BlockedURI: b.BlockedURL,
+			EffectiveDirective: b.EffectiveDirective, OriginalPolicy: b.OriginalPolicy, Disposition: b.Disposition,
+			SourceFile: b.SourceFile, LineNumber: b.LineNumber, ColumnNumber: b.ColumnNumber, StatusCode: b.StatusCode,
+		})
+	}
+	return reports
+}
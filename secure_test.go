@@ -0,0 +1,99 @@
+package kai_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestSecureHeadersDefaults(t *testing.T) {
+	app := kai.NewApp()
+	app.Use(kai.SecureHeaders())
+	app.GET("/", func(c *kai.Context) { c.String(http.StatusOK, "ok") })
+
+	res := kaitest.New(t, app).GET("/").Do().
+		ExpectHeader("X-Content-Type-Options", "nosniff").
+		ExpectHeader("X-Frame-Options", "DENY").
+		ExpectHeader("Referrer-Policy", "no-referrer").
+		ExpectHeaderPresent("Content-Security-Policy")
+	if hsts := res.Header.Get("Strict-Transport-Security"); hsts != "" {
+		t.Errorf("HSTS sent over plain HTTP: %q", hsts)
+	}
+
+	kaitest.New(t, app).HTTPS().GET("/").Do().
+		ExpectHeader("Strict-Transport-Security", "max-age=63072000; includeSubDomains")
+}
+
+func TestSecureHeadersCustom(t *testing.T) {
+	opts := kai.DefaultSecureHeaders()
+	opts.FrameOptions = ""
+	opts.ContentSecurityPolicy = "script-src 'self' 'nonce-{nonce}';"
+	opts.CSPReportOnly = true
+	opts.CSPReportURI = "/csp-report"
+	opts.HSTSMaxAge = time.Hour
+	opts.HSTSIncludeSubdomains = false
+	opts.HSTSPreload = true
+	opts.TrustForwardedProto = true
+
+	app := kai.NewApp()
+	app.Use(kai.SecureHeaders(opts))
+	app.GET("/", func(c *kai.Context) {
+		c.String(http.StatusOK, `<script nonce="`+c.CSPNonce()+`"></script>`)
+	})
+	client := kaitest.New(t, app)
+
+	res := client.GET("/").Header("X-Forwarded-Proto", "https").Do().
+		ExpectHeader("Strict-Transport-Security", "max-age=3600; preload")
+	if _, ok := res.Header["X-Frame-Options"]; ok {
+		t.Error("empty FrameOptions still sent")
+	}
+	if res.Header.Get("Content-Security-Policy") != "" {
+		t.Error("report-only policy was also enforced")
+	}
+
+	policy := res.Header.Get("Content-Security-Policy-Report-Only")
+	nonce := strings.TrimSuffix(strings.TrimPrefix(res.String(), `<script nonce="`), `"></script>`)
+	if nonce == "" || policy != "script-src 'self' 'nonce-"+nonce+"'; report-uri /csp-report" {
+		t.Errorf("policy = %q, nonce = %q", policy, nonce)
+	}
+	if next := client.GET("/").Do().Header.Get("Content-Security-Policy-Report-Only"); next == policy {
+		t.Error("nonce reused across requests")
+	}
+}
+
+func TestCSPReportHandler(t *testing.T) {
+	var reports []kai.CSPReport
+	app := kai.NewApp()
+	app.POST("/csp-report", kai.CSPReportHandler(func(c *kai.Context, report kai.CSPReport) {
+		reports = append(reports, report)
+	}))
+	client := kaitest.New(t, app)
+
+	client.POST("/csp-report").Body("application/csp-report", strings.NewReader(`{"csp-report": {
+		"document-uri": "https://example.com/page",
+		"blocked-uri": "https://evil.example/x.js",
+		"violated-directive": "script-src",
+		"line-number": 12
+	}}`)).Do().ExpectStatus(http.StatusNoContent)
+
+	client.POST("/csp-report").Body("application/reports+json", strings.NewReader(`[
+		{"type": "csp-violation", "body": {"documentURL": "https://example.com/a", "blockedURL": "inline", "effectiveDirective": "style-src-elem"}},
+		{"type": "deprecation", "body": {}}
+	]`)).Do().ExpectStatus(http.StatusNoContent)
+
+	client.POST("/csp-report").Body("text/plain", strings.NewReader("garbage")).Do().ExpectStatus(http.StatusNoContent)
+
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports: %+v", len(reports), reports)
+	}
+	if r := reports[0]; r.EffectiveDirective != "script-src" || r.BlockedURI != "https://evil.example/x.js" || r.LineNumber != 12 {
+		t.Errorf("legacy report = %+v", r)
+	}
+	if r := reports[1]; r.DocumentURI != "https://example.com/a" || r.EffectiveDirective != "style-src-elem" {
+		t.Errorf("reporting API report = %+v", r)
+	}
+}
@@ -0,0 +1,420 @@
+package kai
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SessionRecord is what a SessionStore persists for one session.
+type SessionRecord struct {
+	ID        string         `json:"id"`
+	Values    map[string]any `json:"values"`
+	CreatedAt time.Time      `json:"created_at"`
+	LastSeen  time.Time      `json:"last_seen"`
+}
+
+// SessionStore loads and saves sessions. The cookie value is whatever the
+// store needs to find the session again: an ID for server-side stores, the
+// sealed session itself for the cookie store.
+type SessionStore interface {
+	// Load returns the session for a cookie value, or nil and no error if
+	// it is unknown or expired.
+	Load(cookie string) (*SessionRecord, error)
+	// Save persists rec until expires and returns the cookie value to send.
+	Save(rec *SessionRecord, expires time.Time) (cookie string, err error)
+	// Delete forgets the session for a cookie value.
+	Delete(cookie string) error
+}
+
+// ---------------------------
+// Memory Store
+// ---------------------------
+
+// MemorySessionStore keeps sessions in process memory. Sessions are lost on
+// restart and not shared between instances, so it suits development and
+// single-instance deployments.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+	saves    int
+}
+
+type memorySession struct {
+	rec     SessionRecord
+	expires time.Time
+}
+
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+func (s *MemorySessionStore) Load(cookie string) (*SessionRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.sessions[cookie]
+	if !ok {
+		return nil, nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(s.sessions, cookie)
+		return nil, nil
+	}
+	rec := entry.rec
+	rec.Values = maps.Clone(rec.Values)
+	return &rec, nil
+}
+
+func (s *MemorySessionStore) Save(rec *SessionRecord, expires time.Time) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *rec
+	stored.Values = maps.Clone(rec.Values)
+	s.sessions[rec.ID] = memorySession{rec: stored, expires: expires}
+
+	// Sweep expired sessions now and then instead of running a goroutine.
+	s.saves++
+	if s.saves%1000 == 0 {
+		now := time.Now()
+		for id, entry := range s.sessions {
+			if now.After(entry.expires) {
+				delete(s.sessions, id)
+			}
+		}
+	}
+	return rec.ID, nil
+}
+
+func (s *MemorySessionStore) Delete(cookie string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, cookie)
+	return nil
+}
+
+// ---------------------------
+// Cookie Store
+// ---------------------------
+
+// ErrSessionTooLarge is returned by CookieSessionStore when the sealed
+// session does not fit in a browser cookie.
+var ErrSessionTooLarge = errors.New("kai: session exceeds the 4096 byte cookie limit")
+
+// CookieSessionStore keeps the whole session in the cookie, JSON-encoded and
+// encrypted (or only signed, if the codec has no encryption keys). Nothing
+// is stored on the server, so Delete cannot revoke a copied cookie before it
+// expires. Values come back as JSON types: numbers are float64.
+type CookieSessionStore struct {
+	codec *CookieCodec
+}
+
+// sessionCookieBinding is the name sealed session values are bound to.
+const sessionCookieBinding = "kai.session"
+
+// NewCookieSessionStore returns a store using codec, usually app.CookieCodec()
+// after App.SetCookieKeys.
+func NewCookieSessionStore(codec *CookieCodec) *CookieSessionStore {
+	if codec == nil {
+		panic("Kai sessions: cookie store needs a CookieCodec; call App.SetCookieKeys first")
+	}
+	return &CookieSessionStore{codec: codec}
+}
+
+func (s *CookieSessionStore) Load(cookie string) (*SessionRecord, error) {
+	var data string
+	var err error
+	if len(s.codec.aeads) > 0 {
+		data, err = s.codec.Decrypt(sessionCookieBinding, cookie)
+	} else {
+		data, err = s.codec.Verify(sessionCookieBinding, cookie)
+	}
+	if err != nil {
+		return nil, nil
+	}
+	var rec SessionRecord
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+func (s *CookieSessionStore) Save(rec *SessionRecord, expires time.Time) (string, error) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	var cookie string
+	if len(s.codec.aeads) > 0 {
+		cookie, err = s.codec.Encrypt(sessionCookieBinding, string(data), expires)
+	} else {
+		cookie, err = s.codec.Sign(sessionCookieBinding, string(data), expires)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(cookie) > 4000 {
+		return "", ErrSessionTooLarge
+	}
+	return cookie, nil
+}
+
+func (s *CookieSessionStore) Delete(cookie string) error {
+	return nil
+}
+
+// ---------------------------
+// Sessions Middleware
+// ---------------------------
+
+type SessionOptions struct {
+	// CookieName defaults to "kai_session".
+	CookieName string
+	// Cookie sets Path, Domain, Secure, SameSite and Partitioned of the session
+	// cookie. The cookie is always HttpOnly; SameSite defaults to Lax.
+	Cookie CookieOptions
+	// IdleTimeout ends a session not used for this long. Defaults to 30 minutes.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends a session this long after it was created, however
+	// active. Defaults to 24 hours.
+	AbsoluteTimeout time.Duration
+}
+
+const sessionKey = "kai.session"
+
+const flashKey = "_flash"
+
+// Sessions loads the session named by the request cookie before the handler
+// runs and saves it, setting the cookie, just before the response is
+// written. New sessions are only persisted once something is stored in them.
+func Sessions(store SessionStore, opts ...SessionOptions) HandlerFunc {
+	o := firstOr(opts)
+	if o.CookieName == "" {
+		o.CookieName = "kai_session"
+	}
+	if o.Cookie.SameSite == 0 {
+		o.Cookie.SameSite = http.SameSiteLaxMode
+	}
+	o.Cookie.HttpOnly = true
+	if o.IdleTimeout <= 0 {
+		o.IdleTimeout = 30 * time.Minute
+	}
+	if o.AbsoluteTimeout <= 0 {
+		o.AbsoluteTimeout = 24 * time.Hour
+	}
+
+	return func(c *Context) {
+		s := &Session{store: store, opts: &o, ctx: c}
+		if cookie, err := c.Cookie(o.CookieName); err == nil && cookie != "" {
+			rec, err := store.Load(cookie)
+			if err != nil {
+				c.AddError(err)
+			}
+			now := time.Now()
+			if rec != nil && now.Sub(rec.LastSeen) < o.IdleTimeout && now.Sub(rec.CreatedAt) < o.AbsoluteTimeout {
+				s.rec = *rec
+				s.cookie = cookie
+			} else if rec != nil {
+				store.Delete(cookie)
+			}
+		}
+		if s.rec.Values == nil {
+			s.rec.Values = make(map[string]any)
+		}
+		c.Set(sessionKey, s)
+
+		sw := &sessionWriter{ResponseWriter: c.Writer, session: s}
+		c.Writer = sw
+		c.Next()
+		c.Writer = sw.ResponseWriter
+		sw.commit()
+	}
+}
+
+// Session returns the request's session. It panics if the Sessions
+// middleware is not installed.
+func (c *Context) Session() *Session {
+	value, _ := c.Get(sessionKey)
+	s, ok := value.(*Session)
+	if !ok {
+		panic("Kai sessions: Sessions middleware is not installed")
+	}
+	return s
+}
+
+// Session is the per-request view of a session. It is not safe for use by
+// multiple goroutines.
+type Session struct {
+	store     SessionStore
+	opts      *SessionOptions
+	ctx       *Context
+	rec       SessionRecord
+	cookie    string // cookie value the session was loaded from, "" if new
+	dirty     bool
+	destroyed bool
+	saved     bool
+}
+
+// ID returns the session ID, or "" for a new session that has not stored anything yet.
+func (s *Session) ID() string {
+	return s.rec.ID
+}
+
+// IsNew reports whether the session did not come from the request.
+func (s *Session) IsNew() bool {
+	return s.cookie == ""
+}
+
+func (s *Session) Get(key string) any {
+	return s.rec.Values[key]
+}
+
+func (s *Session) Set(key string, value any) {
+	s.rec.Values[key] = value
+	s.dirty = true
+}
+
+func (s *Session) Delete(key string) {
+	delete(s.rec.Values, key)
+	s.dirty = true
+}
+
+// Clear removes every value but keeps the session.
+func (s *Session) Clear() {
+	clear(s.rec.Values)
+	s.dirty = true
+}
+
+// Regenerate gives the session a new ID and drops the old one from the
+// store, keeping its values. Call it on login and privilege changes to
+// prevent session fixation.
+func (s *Session) Regenerate() error {
+	if s.cookie != "" {
+		if err := s.store.Delete(s.cookie); err != nil {
+			return err
+		}
+		s.cookie = ""
+	}
+	s.rec.ID = ""
+	s.rec.CreatedAt = time.Time{}
+	s.dirty = true
+	return nil
+}
+
+// Destroy deletes the session from the store and expires the cookie.
+func (s *Session) Destroy() error {
+	s.destroyed = true
+	clear(s.rec.Values)
+	if s.cookie == "" {
+		return nil
+	}
+	return s.store.Delete(s.cookie)
+}
+
+// AddFlash queues a message for the next request that calls Flashes.
+func (s *Session) AddFlash(message any) {
+	flashes, _ := s.rec.Values[flashKey].([]any)
+	s.Set(flashKey, append(flashes, message))
+}
+
+// Flashes returns and removes the queued flash messages.
+func (s *Session) Flashes() []any {
+	flashes, ok := s.rec.Values[flashKey].([]any)
+	if !ok {
+		return nil
+	}
+	s.Delete(flashKey)
+	return flashes
+}
+
+// save persists the session and writes its cookie header. It runs once,
+// before the first byte of the response.
+func (s *Session) save() error {
+	if s.saved {
+		return nil
+	}
+	s.saved = true
+	c := s.ctx
+
+	if s.destroyed {
+		if _, err := c.Request.Cookie(s.opts.CookieName); err == nil {
+			c.DeleteCookie(s.opts.CookieName, s.opts.Cookie)
+		}
+		return nil
+	}
+	if s.cookie == "" && !s.dirty {
+		return nil
+	}
+
+	now := time.Now()
+	if s.rec.ID == "" {
+		id, err := newSessionID()
+		if err != nil {
+			return err
+		}
+		s.rec.ID = id
+		s.rec.CreatedAt = now
+	}
+	s.rec.LastSeen = now
+
+	expires := now.Add(s.opts.IdleTimeout)
+	if absolute := s.rec.CreatedAt.Add(s.opts.AbsoluteTimeout); absolute.Before(expires) {
+		expires = absolute
+	}
+	cookie, err := s.store.Save(&s.rec, expires)
+	if err != nil {
+		return err
+	}
+	o := s.opts.Cookie
+	o.Expires = expires
+	o.MaxAge = int(time.Until(expires).Seconds())
+	c.SetCookie(s.opts.CookieName, cookie, o)
+	return nil
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// sessionWriter saves the session when the handler starts writing, since
+// the cookie header cannot be added afterwards.
+type sessionWriter struct {
+	http.ResponseWriter
+	session *Session
+}
+
+func (w *sessionWriter) commit() {
+	if err := w.session.save(); err != nil {
+		w.session.ctx.AddError(err)
+	}
+}
+
+func (w *sessionWriter) WriteHeader(status int) {
+	w.commit()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *sessionWriter) Write(p []byte) (int, error) {
+	w.commit()
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *sessionWriter) Flush() {
+	w.commit()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *sessionWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
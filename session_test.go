@@ -0,0 +1,118 @@
+package kai_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func newSessionApp(store func(app *kai.App) kai.SessionStore, opts ...kai.SessionOptions) *kai.App {
+	app := kai.NewApp()
+	app.SetCookieKeys(kai.CookieKeys{Signing: [][]byte{signingKey}, Encryption: [][]byte{encryptionKey}})
+	app.Use(kai.Sessions(store(app), opts...))
+	app.GET("/", func(c *kai.Context) {
+		s := c.Session()
+		c.JSON(http.StatusOK, map[string]any{"id": s.ID(), "new": s.IsNew(), "user": s.Get("user")})
+	})
+	app.POST("/login", func(c *kai.Context) {
+		s := c.Session()
+		if err := s.Regenerate(); err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		s.Set("user", c.Query("user"))
+		s.AddFlash("welcome")
+		c.Status(http.StatusNoContent)
+	})
+	app.GET("/flashes", func(c *kai.Context) {
+		c.JSON(http.StatusOK, map[string]any{"flashes": c.Session().Flashes()})
+	})
+	app.POST("/logout", func(c *kai.Context) {
+		if err := c.Session().Destroy(); err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+	return app
+}
+
+var sessionStores = map[string]func(app *kai.App) kai.SessionStore{
+	"memory": func(*kai.App) kai.SessionStore { return kai.NewMemorySessionStore() },
+	"cookie": func(app *kai.App) kai.SessionStore { return kai.NewCookieSessionStore(app.CookieCodec()) },
+}
+
+func TestSessionsLifecycle(t *testing.T) {
+	for name, store := range sessionStores {
+		t.Run(name, func(t *testing.T) {
+			client := kaitest.New(t, newSessionApp(store))
+
+			res := client.GET("/").Do().ExpectJSON(map[string]any{"id": "", "new": true, "user": nil})
+			if res.Cookie("kai_session") != nil {
+				t.Error("an untouched session set a cookie")
+			}
+
+			res = client.POST("/login").Query("user", "alice").Do().ExpectStatus(http.StatusNoContent)
+			cookie := res.Cookie("kai_session")
+			if cookie == nil || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode || cookie.MaxAge <= 0 {
+				t.Fatalf("session cookie = %+v", cookie)
+			}
+
+			client.GET("/").Do().ExpectJSONPath("user", "alice").ExpectJSONPath("new", false)
+			client.GET("/flashes").Do().ExpectJSON(map[string]any{"flashes": []string{"welcome"}})
+			client.GET("/flashes").Do().ExpectJSON(map[string]any{"flashes": nil})
+
+			res = client.POST("/logout").Do()
+			if expired := res.Cookie("kai_session"); expired == nil || expired.MaxAge >= 0 {
+				t.Errorf("logout cookie = %+v", expired)
+			}
+			client.GET("/").Do().ExpectJSONPath("new", true).ExpectJSONPath("user", nil)
+		})
+	}
+}
+
+func TestSessionsRegenerateDropsOldID(t *testing.T) {
+	app := newSessionApp(sessionStores["memory"])
+	client := kaitest.New(t, app)
+
+	first := client.POST("/login").Query("user", "alice").Do().Cookie("kai_session")
+	second := client.POST("/login").Query("user", "bob").Do().Cookie("kai_session")
+	if first.Value == second.Value {
+		t.Fatal("Regenerate kept the session ID")
+	}
+
+	// A fixated or stolen pre-login cookie no longer resolves to a session.
+	kaitest.New(t, app).GET("/").Cookie(first).Do().ExpectJSONPath("new", true)
+	kaitest.New(t, app).GET("/").Cookie(second).Do().ExpectJSONPath("user", "bob")
+}
+
+func TestSessionsRejectTamperedCookie(t *testing.T) {
+	app := newSessionApp(sessionStores["cookie"])
+	cookie := kaitest.New(t, app).POST("/login").Query("user", "alice").Do().Cookie("kai_session")
+	if strings.Contains(cookie.Value, "alice") {
+		t.Errorf("cookie store leaked the session in clear: %s", cookie.Value)
+	}
+
+	flipped := "A"
+	if cookie.Value[0] == 'A' {
+		flipped = "B"
+	}
+	cookie.Value = flipped + cookie.Value[1:]
+	kaitest.New(t, app).GET("/").Cookie(cookie).Do().ExpectJSONPath("new", true)
+}
+
+func TestSessionsIdleTimeout(t *testing.T) {
+	app := newSessionApp(sessionStores["memory"], kai.SessionOptions{CookieName: "sid", IdleTimeout: 50 * time.Millisecond})
+	client := kaitest.New(t, app)
+
+	// The cookie is sent by hand: a browser may keep it past its expiry, and
+	// the server must not honor it then.
+	cookie := client.POST("/login").Query("user", "alice").Do().Cookie("sid")
+	client.GET("/").Cookie(cookie).Do().ExpectJSONPath("user", "alice")
+	time.Sleep(80 * time.Millisecond)
+	client.GET("/").Cookie(cookie).Do().ExpectJSONPath("new", true)
+}
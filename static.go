@@ -0,0 +1,299 @@
+package kai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+type StaticOptions struct {
+	// Index is the file served for directory requests. Defaults to "index.html".
+	Index string
+	// Browse renders an HTML listing for directories without an index file.
+	Browse bool
+	// SPA serves the root index file for any path that does not exist, so
+	// client-side routers can handle it.
+	SPA bool
+	// MaxAge sets "Cache-Control: public, max-age=..." when non-zero.
+	MaxAge time.Duration
+	// Compress serves a precompressed "<name>.gz" sibling when the client accepts gzip.
+	Compress bool
+}
+
+// Static serves files from a directory on disk under the given URL prefix.
+// Symlinks and ".." segments cannot escape dir.
+func (a *App) Static(prefix, dir string, opts ...StaticOptions) {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		panic("Kai Static: " + err.Error())
+	}
+	a.StaticFS(prefix, root.FS(), opts...)
+}
+
+// StaticFS serves files from any fs.FS (e.g. embed.FS) under the given URL prefix.
+func (a *App) StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions) {
+	pattern := utils.JoinPath(prefix, "/*filepath")
+	handler := staticHandler(fsys, opts...)
+	a.GET(pattern, handler)
+	a.HEAD(pattern, handler)
+}
+
+func (g *Group) Static(prefix, dir string, opts ...StaticOptions) {
+	g.app.Static(g.Prefix+prefix, dir, opts...)
+}
+
+func (g *Group) StaticFS(prefix string, fsys fs.FS, opts ...StaticOptions) {
+	g.app.StaticFS(g.Prefix+prefix, fsys, opts...)
+}
+
+type staticServer struct {
+	fsys   fs.FS
+	cfg    StaticOptions
+	hashes sync.Map // name => ETag for files without a modification time
+}
+
+func staticHandler(fsys fs.FS, opts ...StaticOptions) HandlerFunc {
+	s := &staticServer{fsys: fsys}
+	if len(opts) > 0 {
+		s.cfg = opts[0]
+	}
+	if s.cfg.Index == "" {
+		s.cfg.Index = "index.html"
+	}
+
+	return func(c *Context) {
+		name, ok := cleanFSPath(c.Param("filepath"))
+		if !ok {
+			c.JSON(http.StatusBadRequest, map[string]any{"error": "invalid file path"})
+			return
+		}
+		s.serve(c, name)
+	}
+}
+
+// cleanFSPath turns a request path into an fs.FS name, rejecting anything
+// that could step outside the root.
+func cleanFSPath(p string) (string, bool) {
+	if strings.ContainsAny(p, "\\\x00") {
+		return "", false
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+	name := strings.TrimPrefix(path.Clean("/"+p), "/")
+	if name == "" {
+		name = "."
+	}
+	return name, fs.ValidPath(name)
+}
+
+func (s *staticServer) serve(c *Context, name string) {
+	info, err := fs.Stat(s.fsys, name)
+	if err != nil {
+		s.notFound(c)
+		return
+	}
+
+	if info.IsDir() {
+		reqPath := c.Request.URL.Path
+		if !strings.HasSuffix(reqPath, "/") {
+			target := reqPath + "/"
+			if c.Request.URL.RawQuery != "" {
+				target += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusMovedPermanently, target)
+			return
+		}
+
+		index := path.Join(name, s.cfg.Index)
+		if indexInfo, err := fs.Stat(s.fsys, index); err == nil && !indexInfo.IsDir() {
+			s.serveFile(c, index, indexInfo)
+			return
+		}
+		if s.cfg.Browse {
+			s.listDir(c, name)
+			return
+		}
+		s.notFound(c)
+		return
+	}
+
+	s.serveFile(c, name, info)
+}
+
+func (s *staticServer) notFound(c *Context) {
+	if s.cfg.SPA {
+		if info, err := fs.Stat(s.fsys, s.cfg.Index); err == nil && !info.IsDir() {
+			s.serveFile(c, s.cfg.Index, info)
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, map[string]any{"error": "file not found"})
+}
+
+func (s *staticServer) serveFile(c *Context, name string, info fs.FileInfo) {
+	h := c.Writer.Header()
+
+	servedName, servedInfo := name, info
+	if s.cfg.Compress {
+		h.Add("Vary", "Accept-Encoding")
+		if acceptsEncoding(c.Request, "gzip") {
+			if gzInfo, err := fs.Stat(s.fsys, name+".gz"); err == nil && !gzInfo.IsDir() {
+				servedName, servedInfo = name+".gz", gzInfo
+				h.Set("Content-Encoding", "gzip")
+			}
+		}
+	}
+
+	if ctype := mime.TypeByExtension(path.Ext(name)); ctype != "" {
+		h.Set("Content-Type", ctype)
+	}
+	if s.cfg.MaxAge > 0 {
+		h.Set("Cache-Control", "public, max-age="+strconv.Itoa(int(s.cfg.MaxAge.Seconds())))
+	}
+
+	if err := serveFSContent(c, s.fsys, servedName, servedInfo, &s.hashes); err != nil {
+		h.Del("Content-Encoding")
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to read file"})
+	}
+}
+
+// serveFSContent writes a regular file with ETag and Last-Modified validators.
+// Conditional and range requests are handled by http.ServeContent.
+func serveFSContent(c *Context, fsys fs.FS, name string, info fs.FileInfo, hashes *sync.Map) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		content = bytes.NewReader(data)
+	}
+
+	etag, err := fileETag(name, info, content, hashes)
+	if err != nil {
+		return err
+	}
+	if c.Writer.Header().Get("ETag") == "" {
+		c.SetHeader("ETag", etag)
+	}
+
+	c.serveHTTP(func(w http.ResponseWriter) {
+		http.ServeContent(w, c.Request, info.Name(), info.ModTime(), content)
+	})
+	return nil
+}
+
+// serveHTTP runs a net/http file server function on the response and records
+// the status it sent (200, 206, 304, ...) in c.StatusCode for Logger and
+// other middleware.
+func (c *Context) serveHTTP(serve func(w http.ResponseWriter)) {
+	sw := &statusWriter{ResponseWriter: c.Writer}
+	serve(sw)
+	if sw.status != 0 {
+		c.StatusCode = sw.status
+		c.wroteHeader = true
+	}
+}
+
+// fileETag derives a validator from size and modification time. Files without
+// a modification time (embed.FS) are hashed once and cached by name.
+func fileETag(name string, info fs.FileInfo, content io.ReadSeeker, hashes *sync.Map) (string, error) {
+	if !info.ModTime().IsZero() {
+		return fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()), nil
+	}
+	if hashes != nil {
+		if cached, ok := hashes.Load(name); ok {
+			return cached.(string), nil
+		}
+	}
+
+	sum := sha256.New()
+	if _, err := io.Copy(sum, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(sum.Sum(nil)[:16]) + `"`
+	if hashes != nil {
+		hashes.Store(name, etag)
+	}
+	return etag, nil
+}
+
+func (s *staticServer) listDir(c *Context, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		c.AddError(err)
+		c.JSON(http.StatusInternalServerError, map[string]any{"error": "failed to read directory"})
+		return
+	}
+
+	base := c.Request.URL.Path
+	var b strings.Builder
+	b.WriteString("<!doctype html>\n<meta charset=\"utf-8\">\n<title>Index of ")
+	b.WriteString(html.EscapeString(base))
+	b.WriteString("</title>\n<h1>Index of ")
+	b.WriteString(html.EscapeString(base))
+	b.WriteString("</h1>\n<ul>\n")
+	if name != "." {
+		b.WriteString("<li><a href=\"../\">../</a></li>\n")
+	}
+	for _, entry := range entries {
+		display := entry.Name()
+		if entry.IsDir() {
+			display += "/"
+		}
+		href := (&url.URL{Path: display}).String()
+		fmt.Fprintf(&b, "<li><a href=\"./%s\">%s</a></li>\n", html.EscapeString(href), html.EscapeString(display))
+	}
+	b.WriteString("</ul>\n")
+
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if c.Request.Method != http.MethodHead {
+		c.Write([]byte(b.String()))
+	}
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header allows coding.
+func acceptsEncoding(r *http.Request, coding string) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		token, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(token), coding) {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package kai_test
+
+import (
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+var staticFiles = fstest.MapFS{
+	"index.html":    {Data: []byte("<h1>app</h1>"), ModTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	"assets/app.js": {Data: []byte("console.log('hi')"), ModTime: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+}
+
+func TestStaticSPAWithAPIRoutes(t *testing.T) {
+	app := kai.NewApp()
+	app.StaticFS("/", staticFiles, kai.StaticOptions{SPA: true, MaxAge: time.Hour})
+	app.GET("/api/ping", func(c *kai.Context) { c.String(http.StatusOK, "pong") })
+	client := kaitest.New(t, app)
+
+	client.GET("/api/ping").Do().ExpectStatus(http.StatusOK).ExpectBody("pong")
+	client.GET("/assets/app.js").Do().
+		ExpectStatus(http.StatusOK).
+		ExpectBody("console.log('hi')").
+		ExpectHeader("Cache-Control", "public, max-age=3600").
+		ExpectHeaderPresent("ETag")
+	client.GET("/dashboard/settings").Do().ExpectStatus(http.StatusOK).ExpectBody("<h1>app</h1>")
+}
+
+func TestStaticRecordsServedStatus(t *testing.T) {
+	var statuses []int
+	app := kai.NewApp()
+	app.Use(func(c *kai.Context) {
+		c.Next()
+		statuses = append(statuses, c.StatusCode)
+	})
+	app.StaticFS("/static", staticFiles)
+	client := kaitest.New(t, app)
+
+	etag := client.GET("/static/assets/app.js").Do().ExpectStatus(http.StatusOK).Header.Get("ETag")
+	client.GET("/static/assets/app.js").Header("If-None-Match", etag).Do().ExpectStatus(http.StatusNotModified)
+	client.GET("/static/assets/app.js").Header("Range", "bytes=0-6").Do().
+		ExpectStatus(http.StatusPartialContent).
+		ExpectBody("console")
+	client.GET("/static/missing.js").Do().ExpectStatus(http.StatusNotFound)
+
+	want := []int{http.StatusOK, http.StatusNotModified, http.StatusPartialContent, http.StatusNotFound}
+	if len(statuses) != len(want) {
+		t.Fatalf("statuses = %v, want %v", statuses, want)
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses = %v, want %v", statuses, want)
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package kai
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ---------------------------
+// Trace Context (W3C)
+// ---------------------------
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+func (id SpanID) IsValid() bool  { return id != SpanID{} }
+
+// SpanContext is the part of a span that crosses process boundaries.
+type SpanContext struct {
+	TraceID    TraceID
+	SpanID     SpanID
+	Sampled    bool
+	TraceState string
+	// Remote is set when the context was parsed from an incoming request.
+	Remote bool
+}
+
+// ParseTraceparent parses a W3C traceparent header value such as
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(value string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	// Version 00 has exactly four fields; later versions may append more.
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	// The spec allows lowercase hex only; anything else invalidates the header.
+	for _, part := range parts[:4] {
+		if !isLowerHex(part) {
+			return sc, false
+		}
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || !sc.TraceID.IsValid() {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || !sc.SpanID.IsValid() {
+		return sc, false
+	}
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags&0x01 == 1
+	sc.Remote = true
+	return sc, true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if (s[i] < '0' || s[i] > '9') && (s[i] < 'a' || s[i] > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Traceparent formats sc as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// InjectTraceContext adds traceparent and tracestate headers for the span in
+// ctx to an outgoing request, so the next service continues the trace.
+func InjectTraceContext(ctx context.Context, header http.Header) {
+	span := SpanFromContext(ctx)
+	if span == nil {
+		return
+	}
+	header.Set("traceparent", span.data.SpanContext.Traceparent())
+	if span.data.SpanContext.TraceState != "" {
+		header.Set("tracestate", span.data.SpanContext.TraceState)
+	}
+}
+
+// ---------------------------
+// Spans
+// ---------------------------
+
+// SpanKind follows the OpenTelemetry span kinds.
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+	SpanKindProducer SpanKind = 4
+	SpanKindConsumer SpanKind = 5
+)
+
+// SpanStatus follows the OpenTelemetry status codes.
+type SpanStatus int
+
+const (
+	SpanStatusUnset SpanStatus = 0
+	SpanStatusOK    SpanStatus = 1
+	SpanStatusError SpanStatus = 2
+)
+
+// SpanData is a finished span as handed to a SpanExporter.
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	Parent        SpanID
+	Start, End    time.Time
+	Attributes    map[string]any
+	Status        SpanStatus
+	StatusMessage string
+	// Resource describes the service, e.g. {"service.name": "orders"}.
+	Resource map[string]any
+}
+
+// Span is an operation being timed. A nil *Span is valid and does nothing,
+// so handlers can use c.Span() whether or not tracing is enabled.
+type Span struct {
+	tracer *Tracer
+	mu     sync.Mutex
+	data   SpanData
+	ended  bool
+}
+
+type spanContextKey struct{}
+
+// SpanFromContext returns the current span stored in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// Span returns the server span of the request, or nil without tracing.
+func (c *Context) Span() *Span {
+	return SpanFromContext(c.Request.Context())
+}
+
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+func (s *Span) SetName(name string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Name = name
+	s.mu.Unlock()
+}
+
+// SetAttribute records a string, bool, integer or float attribute.
+func (s *Span) SetAttribute(key string, value any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Attributes[key] = value
+	s.mu.Unlock()
+}
+
+func (s *Span) SetStatus(status SpanStatus, message string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.data.Status = status
+	s.data.StatusMessage = message
+	s.mu.Unlock()
+}
+
+// RecordError marks the span as failed with err's message.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.SetAttribute("exception.message", err.Error())
+	s.SetStatus(SpanStatusError, err.Error())
+}
+
+// End finishes the span and queues it for export if it is sampled. Calls
+// after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if data.SpanContext.Sampled {
+		s.tracer.enqueue(data)
+	}
+}
+
+// StartSpan starts a child of the span in ctx using the same tracer. Without
+// a span in ctx it returns ctx and a nil (no-op) span.
+func StartSpan(ctx context.Context, name string, kind ...SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+	return parent.tracer.Start(ctx, name, kind...)
+}
+
+// ---------------------------
+// Tracer
+// ---------------------------
+
+// SpanExporter sends finished spans to a tracing backend.
+type SpanExporter interface {
+	ExportSpans(ctx context.Context, spans []SpanData) error
+	Shutdown(ctx context.Context) error
+}
+
+type TracerOptions struct {
+	// ServiceName is reported as the service.name resource attribute.
+	ServiceName string
+	Exporter    SpanExporter
+	// SampleRatio is the fraction of new traces recorded, 0 to 1. Requests
+	// continuing a trace follow the caller's sampled flag. Defaults to 1.
+	SampleRatio *float64
+	// BatchSize and FlushInterval control export batching. Default 512 spans
+	// and 5 seconds.
+	BatchSize     int
+	FlushInterval time.Duration
+	// QueueSize bounds spans waiting for export; more are dropped. Defaults to 2048.
+	QueueSize int
+	// ResponseHeader echoes the server span's traceparent on responses so
+	// clients can look the trace up.
+	ResponseHeader bool
+	// OnError is called with export errors. Defaults to ignoring them.
+	OnError func(err error)
+}
+
+// Tracer creates spans and exports them in batches from a background goroutine.
+type Tracer struct {
+	opts      TracerOptions
+	resource  map[string]any
+	threshold uint64
+
+	queue    chan SpanData
+	flushReq chan chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+func NewTracer(opts TracerOptions) *Tracer {
+	if opts.Exporter == nil {
+		panic("Kai tracing: an Exporter is required")
+	}
+	if opts.ServiceName == "" {
+		opts.ServiceName = "kai"
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 512
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = 5 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2048
+	}
+	ratio := 1.0
+	if opts.SampleRatio != nil {
+		ratio = math.Max(0, math.Min(1, *opts.SampleRatio))
+	}
+
+	t := &Tracer{
+		opts:     opts,
+		resource: map[string]any{"service.name": opts.ServiceName},
+		queue:    make(chan SpanData, opts.QueueSize),
+		flushReq: make(chan chan struct{}),
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	// Sample by comparing the low 8 bytes of the trace ID with a threshold,
+	// so every service sampling at the same ratio agrees on a trace.
+	if ratio >= 1 {
+		t.threshold = math.MaxUint64
+	} else {
+		t.threshold = uint64(ratio * math.MaxUint64)
+	}
+	go t.run()
+	return t
+}
+
+// Start begins a span named name as a child of the span in ctx, or as the
+// root of a new trace, and returns a context carrying it. Defaults to
+// SpanKindInternal.
+func (t *Tracer) Start(ctx context.Context, name string, kind ...SpanKind) (context.Context, *Span) {
+	k := SpanKindInternal
+	if len(kind) > 0 {
+		k = kind[0]
+	}
+	var parent SpanContext
+	if p := SpanFromContext(ctx); p != nil {
+		parent = p.SpanContext()
+	}
+	span := t.newSpan(name, k, parent)
+	return ContextWithSpan(ctx, span), span
+}
+
+func (t *Tracer) newSpan(name string, kind SpanKind, parent SpanContext) *Span {
+	sc := SpanContext{TraceID: parent.TraceID, TraceState: parent.TraceState, Sampled: parent.Sampled}
+	if !parent.TraceID.IsValid() {
+		rand.Read(sc.TraceID[:])
+		sc.Sampled = t.sample(sc.TraceID)
+	}
+	rand.Read(sc.SpanID[:])
+	return &Span{
+		tracer: t,
+		data: SpanData{
+			Name:        name,
+			Kind:        kind,
+			SpanContext: sc,
+			Parent:      parent.SpanID,
+			Start:       time.Now(),
+			Attributes:  make(map[string]any),
+			Resource:    t.resource,
+		},
+	}
+}
+
+func (t *Tracer) sample(id TraceID) bool {
+	if t.threshold == math.MaxUint64 {
+		return true
+	}
+	var v uint64
+	for _, b := range id[8:] {
+		v = v<<8 | uint64(b)
+	}
+	return v < t.threshold
+}
+
+func (t *Tracer) enqueue(data SpanData) {
+	select {
+	case <-t.done:
+	case t.queue <- data:
+	default:
+		// Queue full: drop rather than block the request.
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(t.opts.FlushInterval)
+	defer ticker.Stop()
+	batch := make([]SpanData, 0, t.opts.BatchSize)
+
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := t.opts.Exporter.ExportSpans(ctx, batch)
+		cancel()
+		if err != nil && t.opts.OnError != nil {
+			t.opts.OnError(err)
+		}
+		batch = make([]SpanData, 0, t.opts.BatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case data := <-t.queue:
+				batch = append(batch, data)
+				if len(batch) >= t.opts.BatchSize {
+					export()
+				}
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case data := <-t.queue:
+			batch = append(batch, data)
+			if len(batch) >= t.opts.BatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-t.flushReq:
+			drain()
+			export()
+			close(ack)
+		case <-t.done:
+			drain()
+			export()
+			return
+		}
+	}
+}
+
+// ForceFlush exports every queued span before returning.
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case t.flushReq <- ack:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and shuts the exporter down. Spans
+// ended afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.stopOnce.Do(func() { close(t.done) })
+	select {
+	case <-t.stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return t.opts.Exporter.Shutdown(ctx)
+}
+
+// Middleware starts a server span per request, continuing the trace from
+// an incoming traceparent header. The span is named "METHOD /route/:pattern"
+// and stored in the request context, where c.Span, SpanFromContext and
+// StartSpan find it. Register it early with app.Use.
+func (t *Tracer) Middleware() HandlerFunc {
+	return func(c *Context) {
+		parent, _ := ParseTraceparent(c.Request.Header.Get("traceparent"))
+		if parent.TraceID.IsValid() {
+			parent.TraceState = c.Request.Header.Get("tracestate")
+		}
+
+		name := c.Request.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+		span := t.newSpan(name, SpanKindServer, parent)
+		attrs := span.data.Attributes
+		attrs["http.request.method"] = c.Request.Method
+		attrs["url.path"] = c.Request.URL.Path
+		attrs["server.address"] = c.Request.Host
+		attrs["client.address"] = clientIP(c.Request)
+		if route := c.FullPath(); route != "" {
+			attrs["http.route"] = route
+		}
+		if ua := c.Request.UserAgent(); ua != "" {
+			attrs["user_agent.original"] = ua
+		}
+
+		c.Request = c.Request.WithContext(ContextWithSpan(c.Request.Context(), span))
+		if t.opts.ResponseHeader {
+			c.SetHeader("traceparent", span.data.SpanContext.Traceparent())
+		}
+		sw := &statusWriter{ResponseWriter: c.Writer}
+		c.Writer = sw
+
+		defer func() {
+			c.Writer = sw.ResponseWriter
+			status := sw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if p := recover(); p != nil {
+				span.SetAttribute("http.response.status_code", http.StatusInternalServerError)
+				span.SetStatus(SpanStatusError, fmt.Sprint("panic: ", p))
+				span.End()
+				panic(p)
+			}
+			span.SetAttribute("http.response.status_code", status)
+			if status >= 500 {
+				span.SetStatus(SpanStatusError, http.StatusText(status))
+			}
+			span.End()
+		}()
+		c.Next()
+	}
+}
+
+// ---------------------------
+// OTLP/HTTP Exporter
+// ---------------------------
+
+type OTLPOptions struct {
+	// Endpoint is the full traces URL. Defaults to "http://localhost:4318/v1/traces".
+	Endpoint string
+	// Headers are added to every export request, e.g. for authentication.
+	Headers map[string]string
+	// Client defaults to an http.Client with a 10 second timeout.
+	Client *http.Client
+}
+
+// OTLPExporter sends spans to an OpenTelemetry collector using OTLP over
+// HTTP with the JSON encoding.
+type OTLPExporter struct {
+	opts OTLPOptions
+}
+
+func NewOTLPExporter(opts ...OTLPOptions) *OTLPExporter {
+	o := firstOr(opts)
+	if o.Endpoint == "" {
+		o.Endpoint = "http://localhost:4318/v1/traces"
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OTLPExporter{opts: o}
+}
+
+func (e *OTLPExporter) ExportSpans(ctx context.Context, spans []SpanData) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opts.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("kai: OTLP export failed: " + resp.Status)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+type otlpKeyValue struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+func otlpAttributes(attrs map[string]any) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for k, v := range attrs {
+		var value map[string]any
+		switch x := v.(type) {
+		case string:
+			value = map[string]any{"stringValue": x}
+		case bool:
+			value = map[string]any{"boolValue": x}
+		case int:
+			value = map[string]any{"intValue": strconv.Itoa(x)}
+		case int64:
+			value = map[string]any{"intValue": strconv.FormatInt(x, 10)}
+		case float64:
+			value = map[string]any{"doubleValue": x}
+		default:
+			value = map[string]any{"stringValue": fmt.Sprint(x)}
+		}
+		out = append(out, otlpKeyValue{Key: k, Value: value})
+	}
+	return out
+}
+
+// otlpRequest builds an ExportTraceServiceRequest in the OTLP JSON mapping,
+// where IDs are hex strings and 64-bit integers are decimal strings.
+func otlpRequest(spans []SpanData) map[string]any {
+	encoded := make([]map[string]any, 0, len(spans))
+	for _, s := range spans {
+		span := map[string]any{
+			"traceId":           s.SpanContext.TraceID.String(),
+			"spanId":            s.SpanContext.SpanID.String(),
+			"name":              s.Name,
+			"kind":              int(s.Kind),
+			"startTimeUnixNano": strconv.FormatInt(s.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(s.End.UnixNano(), 10),
+			"attributes":        otlpAttributes(s.Attributes),
+			"status":            map[string]any{"code": int(s.Status), "message": s.StatusMessage},
+		}
+		if s.Parent.IsValid() {
+			span["parentSpanId"] = s.Parent.String()
+		}
+		if s.SpanContext.TraceState != "" {
+			span["traceState"] = s.SpanContext.TraceState
+		}
+		encoded = append(encoded, span)
+	}
+	return map[string]any{
+		"resourceSpans": []any{map[string]any{
+			"resource": map[string]any{"attributes": otlpAttributes(spans[0].Resource)},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "github.com/dipto-kainin/kai"},
+				"spans": encoded,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,116 @@
+package kai_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	valid := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := kai.ParseTraceparent(valid)
+	if !ok || !sc.Sampled || !sc.Remote || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("ParseTraceparent(%q) = %+v, %v", valid, sc, ok)
+	}
+	if sc.Traceparent() != valid {
+		t.Errorf("Traceparent() = %q, want %q", sc.Traceparent(), valid)
+	}
+
+	for _, value := range []string{
+		"",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", // uppercase trace ID
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00F067AA0BA902B7-01", // uppercase span ID
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0A", // uppercase flags
+		"0g-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", // non-hex version
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", // forbidden version
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01", // zero trace ID
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", // zero span ID
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+	} {
+		if _, ok := kai.ParseTraceparent(value); ok {
+			t.Errorf("ParseTraceparent(%q) accepted an invalid header", value)
+		}
+	}
+	// Future versions may append fields.
+	if _, ok := kai.ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("a higher version with extra fields should parse")
+	}
+}
+
+type memoryExporter struct {
+	mu    sync.Mutex
+	spans []kai.SpanData
+}
+
+func (e *memoryExporter) ExportSpans(_ context.Context, spans []kai.SpanData) error {
+	e.mu.Lock()
+	e.spans = append(e.spans, spans...)
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *memoryExporter) Shutdown(context.Context) error { return nil }
+
+func TestTracerMiddleware(t *testing.T) {
+	exporter := &memoryExporter{}
+	tracer := kai.NewTracer(kai.TracerOptions{ServiceName: "orders", Exporter: exporter, ResponseHeader: true})
+	t.Cleanup(func() { tracer.Shutdown(context.Background()) })
+
+	app := kai.NewApp()
+	app.Use(tracer.Middleware())
+	var outgoing http.Header
+	app.GET("/orders/:id", func(c *kai.Context) {
+		_, child := kai.StartSpan(c.Request.Context(), "load order")
+		child.End()
+		outgoing = make(http.Header)
+		kai.InjectTraceContext(c.Request.Context(), outgoing)
+		c.String(http.StatusOK, "ok")
+	})
+	client := kaitest.New(t, app)
+
+	parent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	res := client.GET("/orders/7").Header("traceparent", parent).Header("tracestate", "vendor=1").Do().
+		ExpectStatus(http.StatusOK)
+	if got := res.Header.Get("traceparent"); !strings.HasPrefix(got, "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Fatalf("response traceparent = %q does not continue the trace", got)
+	}
+	if got := outgoing.Get("traceparent"); !strings.HasPrefix(got, "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Fatalf("injected traceparent = %q does not continue the trace", got)
+	}
+	if outgoing.Get("tracestate") != "vendor=1" {
+		t.Errorf("tracestate = %q, want vendor=1", outgoing.Get("tracestate"))
+	}
+
+	// An uppercase traceparent is invalid and starts a new trace.
+	res = client.GET("/orders/8").Header("traceparent", strings.ToUpper(parent)).Do()
+	if strings.Contains(res.Header.Get("traceparent"), "4bf92f3577b34da6a3ce929d0e0e4736") {
+		t.Error("an invalid traceparent was continued")
+	}
+
+	if err := tracer.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	if len(exporter.spans) != 4 { // a server and a child span per request
+		t.Fatalf("exported %d spans, want 4", len(exporter.spans))
+	}
+	var server *kai.SpanData
+	for i, span := range exporter.spans {
+		if span.Name == "GET /orders/:id" && span.Parent.String() == "00f067aa0ba902b7" {
+			server = &exporter.spans[i]
+		}
+	}
+	if server == nil {
+		t.Fatalf("no server span with the remote parent in %+v", exporter.spans)
+	}
+	if server.Attributes["http.response.status_code"] != http.StatusOK || server.Resource["service.name"] != "orders" {
+		t.Errorf("server span = %+v", server)
+	}
+}
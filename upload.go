@@ -0,0 +1,288 @@
+package kai
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dipto-kainin/kai/utils"
+)
+
+const defaultMultipartMemory = 32 << 20
+
+var (
+	ErrNotMultipart         = utils.NewHTTPError(http.StatusBadRequest, "request is not multipart/form-data")
+	ErrUploadFileTooLarge   = utils.NewHTTPError(http.StatusRequestEntityTooLarge, "uploaded file exceeds size limit")
+	ErrUploadTooLarge       = utils.NewHTTPError(http.StatusRequestEntityTooLarge, "upload exceeds total size limit")
+	ErrUploadFieldTooLarge  = utils.NewHTTPError(http.StatusRequestEntityTooLarge, "form field exceeds size limit")
+	ErrUploadTooManyFiles   = utils.NewHTTPError(http.StatusRequestEntityTooLarge, "too many files in upload")
+	ErrUploadTypeNotAllowed = utils.NewHTTPError(http.StatusUnsupportedMediaType, "file type not allowed")
+)
+
+type UploadOptions struct {
+	// MaxFileSize caps each file part. Zero means unlimited.
+	MaxFileSize int64
+	// MaxTotalSize caps the whole request body. Zero means unlimited.
+	MaxTotalSize int64
+	// MaxFiles caps the number of file parts. Zero means unlimited.
+	MaxFiles int
+	// MaxFieldSize caps each non-file field. Defaults to 1 MiB.
+	MaxFieldSize int64
+	// AllowedTypes is an allowlist of sniffed MIME types; "image/*" style
+	// wildcards are supported. Empty allows everything.
+	AllowedTypes []string
+}
+
+// UploadedFile describes one streamed file part.
+type UploadedFile struct {
+	Field    string
+	Filename string
+	// ContentType is sniffed from the first 512 bytes, not taken from the client.
+	ContentType string
+	Size        int64
+	// Path is set when the file was written to disk by SaveUploads.
+	Path   string
+	Header textproto.MIMEHeader
+}
+
+// UploadForm mirrors multipart.Form for streamed uploads.
+type UploadForm struct {
+	Value map[string][]string
+	File  map[string][]*UploadedFile
+}
+
+// StreamUploads parses a multipart body part by part without buffering files.
+// For each file part, open receives the metadata (after the type check) and
+// returns the destination writer; writers implementing io.Closer are closed.
+// Limit violations are reported as *utils.HTTPError values such as ErrUploadFileTooLarge.
+func (c *Context) StreamUploads(opts UploadOptions, open func(f *UploadedFile) (io.Writer, error)) (*UploadForm, error) {
+	if opts.MaxFieldSize <= 0 {
+		opts.MaxFieldSize = 1 << 20
+	}
+	if opts.MaxTotalSize > 0 {
+		c.Request.Body = &uploadLimitReader{r: c.Request.Body, remaining: opts.MaxTotalSize, closer: c.Request.Body}
+	}
+
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, ErrNotMultipart
+	}
+
+	form := &UploadForm{
+		Value: make(map[string][]string),
+		File:  make(map[string][]*UploadedFile),
+	}
+	files := 0
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return form, nil
+		}
+		if err != nil {
+			return form, uploadError(err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, opts.MaxFieldSize+1))
+			part.Close()
+			if err != nil {
+				return form, uploadError(err)
+			}
+			if int64(len(value)) > opts.MaxFieldSize {
+				return form, ErrUploadFieldTooLarge
+			}
+			form.Value[part.FormName()] = append(form.Value[part.FormName()], string(value))
+			continue
+		}
+
+		files++
+		if opts.MaxFiles > 0 && files > opts.MaxFiles {
+			part.Close()
+			return form, ErrUploadTooManyFiles
+		}
+
+		file, err := streamPart(part, opts, open)
+		part.Close()
+		if file != nil {
+			form.File[file.Field] = append(form.File[file.Field], file)
+		}
+		if err != nil {
+			return form, err
+		}
+	}
+}
+
+func streamPart(part *multipart.Part, opts UploadOptions, open func(f *UploadedFile) (io.Writer, error)) (*UploadedFile, error) {
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(part, sniff)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, uploadError(err)
+	}
+	sniff = sniff[:n]
+
+	file := &UploadedFile{
+		Field:       part.FormName(),
+		Filename:    part.FileName(),
+		ContentType: http.DetectContentType(sniff),
+		Header:      part.Header,
+	}
+	if !mimeAllowed(file.ContentType, opts.AllowedTypes) {
+		return nil, ErrUploadTypeNotAllowed
+	}
+
+	dst, err := open(file)
+	if err != nil {
+		return nil, err
+	}
+	if closer, ok := dst.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	src := io.MultiReader(bytes.NewReader(sniff), part)
+	if opts.MaxFileSize > 0 {
+		src = io.LimitReader(src, opts.MaxFileSize+1)
+	}
+	written, err := io.Copy(dst, src)
+	file.Size = written
+	if err != nil {
+		return file, uploadError(err)
+	}
+	if opts.MaxFileSize > 0 && written > opts.MaxFileSize {
+		return file, ErrUploadFileTooLarge
+	}
+	return file, nil
+}
+
+// SaveUploads streams every file part into dir under a random name that keeps
+// the original extension. On error, files written so far are removed.
+func (c *Context) SaveUploads(dir string, opts UploadOptions) (*UploadForm, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	form, err := c.StreamUploads(opts, func(f *UploadedFile) (io.Writer, error) {
+		name := make([]byte, 16)
+		if _, err := rand.Read(name); err != nil {
+			return nil, err
+		}
+		f.Path = filepath.Join(dir, hex.EncodeToString(name)+safeExt(f.Filename))
+		return os.OpenFile(f.Path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	})
+	if err != nil && form != nil {
+		for _, files := range form.File {
+			for _, f := range files {
+				if f.Path != "" {
+					_ = os.Remove(f.Path)
+				}
+			}
+		}
+	}
+	return form, err
+}
+
+// SaveUploadedFile copies a file from a parsed multipart form to dst without
+// loading it into memory.
+func (c *Context) SaveUploadedFile(header *multipart.FileHeader, dst string) error {
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return copyToFile(dst, src)
+}
+
+func copyToFile(dst string, src io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+func mimeAllowed(contentType string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		if a == mediaType || a == "*/*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(a, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func safeExt(filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == "" || len(ext) > 16 {
+		return ""
+	}
+	for _, r := range ext[1:] {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			return ""
+		}
+	}
+	return ext
+}
+
+func uploadError(err error) error {
+	if errors.Is(err, ErrUploadTooLarge) {
+		return ErrUploadTooLarge
+	}
+	var maxBytes *http.MaxBytesError
+	if errors.As(err, &maxBytes) {
+		return ErrUploadTooLarge
+	}
+	return err
+}
+
+// uploadLimitReader fails with ErrUploadTooLarge once the body exceeds its budget.
+type uploadLimitReader struct {
+	r         io.Reader
+	remaining int64
+	closer    io.Closer
+}
+
+func (l *uploadLimitReader) Read(p []byte) (int, error) {
+	if l.remaining < 0 {
+		return 0, ErrUploadTooLarge
+	}
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, ErrUploadTooLarge
+	}
+	return n, err
+}
+
+func (l *uploadLimitReader) Close() error {
+	return l.closer.Close()
+}
@@ -0,0 +1,120 @@
+package kai_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+	"github.com/dipto-kainin/kai/utils"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+type uploadPart struct {
+	field, filename string
+	data            []byte
+}
+
+func multipartBody(t *testing.T, values map[string]string, files ...uploadPart) (string, *bytes.Buffer) {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range values {
+		w.WriteField(k, v)
+	}
+	for _, f := range files {
+		part, err := w.CreateFormFile(f.field, f.filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(f.data)
+	}
+	w.Close()
+	return w.FormDataContentType(), &body
+}
+
+func newUploadApp(dir string, opts kai.UploadOptions) *kai.App {
+	app := kai.NewApp()
+	app.POST("/media", func(c *kai.Context) {
+		form, err := c.SaveUploads(dir, opts)
+		var httpErr *utils.HTTPError
+		if errors.As(err, &httpErr) {
+			c.JSON(httpErr.Code, map[string]any{"error": httpErr.Message})
+			return
+		}
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.JSON(http.StatusOK, map[string]any{"files": form.File["media"], "title": form.Value["title"]})
+	})
+	return app
+}
+
+func TestSaveUploads(t *testing.T) {
+	dir := t.TempDir()
+	client := kaitest.New(t, newUploadApp(dir, kai.UploadOptions{MaxFileSize: 1024, AllowedTypes: []string{"image/*"}}))
+
+	ctype, body := multipartBody(t, map[string]string{"title": "cat"}, uploadPart{"media", "../../cat.png", pngHeader})
+	var out struct {
+		Files []kai.UploadedFile
+		Title []string
+	}
+	client.POST("/media").Body(ctype, body).Do().ExpectStatus(http.StatusOK).DecodeJSON(&out)
+	if len(out.Files) != 1 || out.Title[0] != "cat" {
+		t.Fatalf("response = %+v", out)
+	}
+	f := out.Files[0]
+	if f.ContentType != "image/png" || f.Size != int64(len(pngHeader)) || f.Filename != "cat.png" {
+		t.Errorf("file = %+v", f)
+	}
+	if data, err := os.ReadFile(f.Path); err != nil || !bytes.Equal(data, pngHeader) {
+		t.Errorf("saved file = %q, %v", data, err)
+	}
+}
+
+func TestSaveUploadsLimits(t *testing.T) {
+	client := kaitest.New(t, newUploadApp(t.TempDir(), kai.UploadOptions{
+		MaxFileSize:  64,
+		MaxFiles:     1,
+		AllowedTypes: []string{"image/*"},
+	}))
+
+	ctype, body := multipartBody(t, nil, uploadPart{"media", "big.png", append(pngHeader, make([]byte, 100)...)})
+	client.POST("/media").Body(ctype, body).Do().
+		ExpectStatus(http.StatusRequestEntityTooLarge).
+		ExpectJSON(map[string]any{"error": "uploaded file exceeds size limit"})
+
+	ctype, body = multipartBody(t, nil, uploadPart{"media", "fake.png", []byte("#!/bin/sh\necho hi")})
+	client.POST("/media").Body(ctype, body).Do().ExpectStatus(http.StatusUnsupportedMediaType)
+
+	ctype, body = multipartBody(t, nil, uploadPart{"media", "a.png", pngHeader}, uploadPart{"media", "b.png", pngHeader})
+	client.POST("/media").Body(ctype, body).Do().
+		ExpectStatus(http.StatusRequestEntityTooLarge).
+		ExpectJSON(map[string]any{"error": "too many files in upload"})
+
+	client.POST("/media").JSON(map[string]string{"a": "b"}).Do().ExpectStatus(http.StatusBadRequest)
+}
+
+func TestStreamUploads(t *testing.T) {
+	app := kai.NewApp()
+	app.POST("/stream", func(c *kai.Context) {
+		var buf bytes.Buffer
+		form, err := c.StreamUploads(kai.UploadOptions{}, func(f *kai.UploadedFile) (io.Writer, error) {
+			return &buf, nil
+		})
+		if err != nil {
+			c.AbortWithError(err)
+			return
+		}
+		c.String(http.StatusOK, form.File["doc"][0].Filename+":"+buf.String())
+	})
+	ctype, body := multipartBody(t, nil, uploadPart{"doc", "notes.txt", []byte("hello")})
+	kaitest.New(t, app).POST("/stream").Body(ctype, body).Do().ExpectStatus(http.StatusOK).ExpectBody("notes.txt:hello")
+}
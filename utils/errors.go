@@ -10,6 +10,8 @@ type HTTPError struct {
 	Code    int
 	Message string
 	Err     error
+	// Header is added to the error response, e.g. a WWW-Authenticate challenge
+	Header http.Header
 }
 
 func (e *HTTPError) Error() string {
@@ -29,6 +31,18 @@ func WrapHTTPError(code int, message string, err error) *HTTPError {
 	return &HTTPError{Code: code, Message: message, Err: err}
 }
 
+// WithHeader returns a copy of e that also sets a response header, leaving
+// shared errors such as ErrUnauthorized untouched
+func (e *HTTPError) WithHeader(key, value string) *HTTPError {
+	cp := *e
+	cp.Header = e.Header.Clone()
+	if cp.Header == nil {
+		cp.Header = make(http.Header)
+	}
+	cp.Header.Set(key, value)
+	return &cp
+}
+
 // Common HTTP errors
 var (
 	ErrBadRequest          = NewHTTPError(http.StatusBadRequest, "Bad Request")
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"crypto/rand"
+	"time"
+)
+
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID returns a ULID: 26 Crockford base32 characters encoding a 48-bit
+// Unix millisecond timestamp and 80 random bits, sortable by creation time
+func NewULID() string {
+	var b [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+	rand.Read(b[6:])
+
+	// 128 bits as 26 five-bit groups; the first group holds only 3 bits.
+	var out [26]byte
+	var acc uint64
+	bits := 0
+	pos := 25
+	for i := 15; i >= 0; i-- {
+		acc |= uint64(b[i]) << bits
+		bits += 8
+		for bits >= 5 && pos >= 0 {
+			out[pos] = crockford[acc&31]
+			acc >>= 5
+			bits -= 5
+			pos--
+		}
+	}
+	if pos >= 0 {
+		out[pos] = crockford[acc&31]
+	}
+	return string(out[:])
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// UUID is an RFC 9562 UUID in its 16-byte binary form
+type UUID [16]byte
+
+var errInvalidUUID = errors.New("invalid UUID format")
+
+// ParseUUID parses the canonical 36-character form, e.g. "f47ac10b-58cc-4372-a567-0e02b2c3d479"
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errInvalidUUID
+	}
+	hexDigits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+	if _, err := hex.Decode(u[:], []byte(hexDigits)); err != nil {
+		return UUID{}, errInvalidUUID
+	}
+	return u, nil
+}
+
+// String returns the canonical lowercase hyphenated form
+func (u UUID) String() string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], u[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], u[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], u[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], u[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:36], u[10:16])
+	return string(buf[:])
+}
+
+// MarshalText encodes the UUID in its canonical form, so it serializes as a JSON string
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+// UnmarshalText parses the canonical form
+func (u *UUID) UnmarshalText(text []byte) error {
+	parsed, err := ParseUUID(string(text))
+	if err != nil {
+		return err
+	}
+	*u = parsed
+	return nil
+}
+
+// NewUUIDv4 returns a random (version 4) UUID
+func NewUUIDv4() UUID {
+	var u UUID
+	rand.Read(u[:])
+	u[6] = u[6]&0x0f | 0x40
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
+
+// NewUUIDv7 returns a time-ordered (version 7) UUID: a 48-bit Unix millisecond
+// timestamp followed by random bits, so IDs sort by creation time
+func NewUUIDv7() UUID {
+	var u UUID
+	rand.Read(u[6:])
+	ms := uint64(time.Now().UnixMilli())
+	u[0] = byte(ms >> 40)
+	u[1] = byte(ms >> 32)
+	u[2] = byte(ms >> 24)
+	u[3] = byte(ms >> 16)
+	u[4] = byte(ms >> 8)
+	u[5] = byte(ms)
+	u[6] = u[6]&0x0f | 0x70
+	u[8] = u[8]&0x3f | 0x80
+	return u
+}
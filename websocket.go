@@ -0,0 +1,785 @@
+package kai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket message types (RFC 6455 opcodes).
+const (
+	WebSocketContinuation = 0x0
+	WebSocketText         = 0x1
+	WebSocketBinary       = 0x2
+	WebSocketClose        = 0x8
+	WebSocketPing         = 0x9
+	WebSocketPong         = 0xA
+)
+
+// WebSocket close codes (RFC 6455 section 7.4.1).
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005
+	CloseAbnormalClosure    = 1006
+	CloseInvalidPayload     = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseMandatoryExtension = 1010
+	CloseInternalServerErr  = 1011
+)
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+var (
+	ErrWebSocketClosed = errors.New("kai: websocket connection closed")
+	ErrCloseSent       = errors.New("kai: websocket close frame already sent")
+)
+
+// CloseError is returned by ReadMessage when the peer sends a close frame
+// or the connection fails with a protocol violation.
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+type WebSocketOptions struct {
+	// Subprotocols lists the server's supported subprotocols in order of preference.
+	Subprotocols []string
+	// CheckOrigin validates the Origin header. Defaults to same-host or no Origin.
+	CheckOrigin func(c *Context) bool
+	// ReadLimit caps the size of a single (reassembled) message. Defaults to 32 MiB.
+	ReadLimit int64
+	// ResponseHeader is added to the 101 Switching Protocols response.
+	ResponseHeader http.Header
+}
+
+// WebSocketConn is a single RFC 6455 connection, either server or client side.
+type WebSocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+	readLimit   int64
+
+	writeMu   sync.Mutex // guards individual frames
+	messageMu sync.Mutex // guards fragmented data messages
+	closeSent bool
+
+	pingHandler func(data []byte) error
+	pongHandler func(data []byte) error
+}
+
+// ---------------------------
+// Server Handshake
+// ---------------------------
+
+// WebSocket wraps a handler so the route upgrades to a WebSocket connection.
+// The connection is closed when the handler returns.
+func WebSocket(handler func(c *Context, ws *WebSocketConn), opts ...WebSocketOptions) HandlerFunc {
+	return func(c *Context) {
+		ws, err := c.Upgrade(opts...)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		handler(c, ws)
+	}
+}
+
+// Upgrade performs the server side of the opening handshake and hijacks the
+// underlying connection. On failure an error response has already been written.
+func (c *Context) Upgrade(opts ...WebSocketOptions) (*WebSocketConn, error) {
+	var cfg WebSocketOptions
+	if len(opts) > 0 {
+		cfg = opts[0]
+	}
+	if cfg.ReadLimit <= 0 {
+		cfg.ReadLimit = 32 << 20
+	}
+	if cfg.CheckOrigin == nil {
+		cfg.CheckOrigin = sameOrigin
+	}
+
+	req := c.Request
+	if req.Method != http.MethodGet {
+		return nil, c.handshakeError(http.StatusMethodNotAllowed, "websocket upgrade requires GET")
+	}
+	if !headerContainsToken(req.Header, "Connection", "upgrade") {
+		return nil, c.handshakeError(http.StatusBadRequest, "missing 'Connection: upgrade' header")
+	}
+	if !headerContainsToken(req.Header, "Upgrade", "websocket") {
+		return nil, c.handshakeError(http.StatusBadRequest, "missing 'Upgrade: websocket' header")
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		return nil, c.handshakeError(http.StatusUpgradeRequired, "unsupported websocket version")
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		return nil, c.handshakeError(http.StatusBadRequest, "invalid Sec-WebSocket-Key")
+	}
+	if !cfg.CheckOrigin(c) {
+		return nil, c.handshakeError(http.StatusForbidden, "websocket origin not allowed")
+	}
+
+	subprotocol := ""
+	for _, offered := range headerTokens(req.Header, "Sec-WebSocket-Protocol") {
+		if contains(cfg.Subprotocols, offered) {
+			subprotocol = offered
+			break
+		}
+	}
+
+	conn, rw, err := http.NewResponseController(c.Writer).Hijack()
+	if err != nil {
+		return nil, c.handshakeError(http.StatusInternalServerError, "websocket upgrade not supported by response writer")
+	}
+
+	var resp strings.Builder
+	resp.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	resp.WriteString("Upgrade: websocket\r\nConnection: Upgrade\r\n")
+	resp.WriteString("Sec-WebSocket-Accept: " + computeAcceptKey(key) + "\r\n")
+	if subprotocol != "" {
+		resp.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	for k, values := range cfg.ResponseHeader {
+		for _, v := range values {
+			resp.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+	resp.WriteString("\r\n")
+
+	// Clear any deadlines set by the http.Server before handing the conn over.
+	_ = conn.SetDeadline(time.Time{})
+	if _, err := conn.Write([]byte(resp.String())); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	c.StatusCode = http.StatusSwitchingProtocols
+	c.wroteHeader = true
+
+	return newWebSocketConn(conn, rw.Reader, true, subprotocol, cfg.ReadLimit), nil
+}
+
+func (c *Context) handshakeError(code int, message string) error {
+	c.JSON(code, map[string]any{"error": message})
+	return errors.New("kai: " + message)
+}
+
+func sameOrigin(c *Context) bool {
+	origin := c.Request.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, c.Request.Host)
+}
+
+func computeAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerTokens(h http.Header, name string) []string {
+	var tokens []string
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, t := range headerTokens(h, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// ---------------------------
+// Client Handshake
+// ---------------------------
+
+// DialWebSocket opens a client connection to a ws:// or wss:// URL.
+// It is primarily intended for testing Kai WebSocket routes.
+func DialWebSocket(ctx context.Context, rawURL string, header http.Header) (*WebSocketConn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	useTLS := false
+	switch u.Scheme {
+	case "ws", "http":
+	case "wss", "https":
+		useTLS = true
+	default:
+		return nil, nil, fmt.Errorf("kai: unsupported websocket scheme %q", u.Scheme)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		if useTLS {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		} else {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	if useTLS {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: u.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, nil, err
+		}
+		conn = tlsConn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(keyBytes)
+
+	reqURL := *u
+	reqURL.Scheme = "http"
+	if useTLS {
+		reqURL.Scheme = "https"
+	}
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        &reqURL,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Host:       u.Host,
+	}
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!headerContainsToken(resp.Header, "Upgrade", "websocket") ||
+		!headerContainsToken(resp.Header, "Connection", "upgrade") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != computeAcceptKey(key) {
+		conn.Close()
+		return nil, resp, fmt.Errorf("kai: websocket handshake failed with status %d", resp.StatusCode)
+	}
+
+	subprotocol := resp.Header.Get("Sec-WebSocket-Protocol")
+	if subprotocol != "" && !contains(headerTokens(req.Header, "Sec-WebSocket-Protocol"), subprotocol) {
+		conn.Close()
+		return nil, resp, errors.New("kai: server selected an unrequested subprotocol")
+	}
+
+	_ = conn.SetDeadline(time.Time{})
+	return newWebSocketConn(conn, br, false, subprotocol, 32<<20), resp, nil
+}
+
+// ---------------------------
+// Connection
+// ---------------------------
+
+func newWebSocketConn(conn net.Conn, br *bufio.Reader, isServer bool, subprotocol string, readLimit int64) *WebSocketConn {
+	if br == nil {
+		br = bufio.NewReader(conn)
+	}
+	ws := &WebSocketConn{
+		conn:        conn,
+		br:          br,
+		isServer:    isServer,
+		subprotocol: subprotocol,
+		readLimit:   readLimit,
+	}
+	ws.pingHandler = func(data []byte) error {
+		return ws.WriteControl(WebSocketPong, data)
+	}
+	ws.pongHandler = func([]byte) error { return nil }
+	return ws
+}
+
+// Subprotocol returns the negotiated subprotocol, if any.
+func (ws *WebSocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+func (ws *WebSocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+func (ws *WebSocketConn) LocalAddr() net.Addr {
+	return ws.conn.LocalAddr()
+}
+
+func (ws *WebSocketConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+func (ws *WebSocketConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// SetReadLimit caps the size of a single reassembled message. Zero or less
+// disables the cap; payloads are then buffered as they arrive.
+func (ws *WebSocketConn) SetReadLimit(limit int64) {
+	ws.readLimit = limit
+}
+
+// SetPingHandler replaces the default handler, which replies with a pong.
+func (ws *WebSocketConn) SetPingHandler(h func(data []byte) error) {
+	ws.pingHandler = h
+}
+
+func (ws *WebSocketConn) SetPongHandler(h func(data []byte) error) {
+	ws.pongHandler = h
+}
+
+// ---------------------------
+// Reading
+// ---------------------------
+
+type wsFrame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+// ReadMessage returns the next complete data message, reassembling fragments
+// and answering control frames along the way. A close frame from the peer is
+// echoed back and reported as a *CloseError.
+func (ws *WebSocketConn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+
+	for {
+		frame, err := ws.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frame.opcode {
+		case WebSocketText, WebSocketBinary:
+			if messageType != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "new message started before previous finished")
+			}
+			messageType = frame.opcode
+			message = frame.payload
+		case WebSocketContinuation:
+			if messageType == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "continuation frame without message")
+			}
+			message = append(message, frame.payload...)
+		case WebSocketPing:
+			if err := ws.pingHandler(frame.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case WebSocketPong:
+			if err := ws.pongHandler(frame.payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case WebSocketClose:
+			return 0, nil, ws.handleClose(frame.payload)
+		}
+
+		if frame.fin {
+			if messageType == WebSocketText && !utf8.Valid(message) {
+				return 0, nil, ws.fail(CloseInvalidPayload, "invalid UTF-8 in text message")
+			}
+			if message == nil {
+				message = []byte{}
+			}
+			return messageType, message, nil
+		}
+	}
+}
+
+func (ws *WebSocketConn) readFrame(buffered int64) (wsFrame, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(ws.br, head[:]); err != nil {
+		return wsFrame{}, ws.readError(err)
+	}
+
+	frame := wsFrame{
+		fin:    head[0]&0x80 != 0,
+		opcode: int(head[0] & 0x0f),
+	}
+	if head[0]&0x70 != 0 {
+		return wsFrame{}, ws.fail(CloseProtocolError, "reserved bits set without negotiated extension")
+	}
+
+	switch frame.opcode {
+	case WebSocketContinuation, WebSocketText, WebSocketBinary:
+	case WebSocketClose, WebSocketPing, WebSocketPong:
+		if !frame.fin {
+			return wsFrame{}, ws.fail(CloseProtocolError, "fragmented control frame")
+		}
+	default:
+		return wsFrame{}, ws.fail(CloseProtocolError, "unknown opcode")
+	}
+
+	masked := head[1]&0x80 != 0
+	if ws.isServer && !masked {
+		return wsFrame{}, ws.fail(CloseProtocolError, "client frame is not masked")
+	}
+	if !ws.isServer && masked {
+		return wsFrame{}, ws.fail(CloseProtocolError, "server frame is masked")
+	}
+
+	length := int64(head[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return wsFrame{}, ws.readError(err)
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return wsFrame{}, ws.readError(err)
+		}
+		raw := binary.BigEndian.Uint64(ext[:])
+		if raw>>63 != 0 {
+			return wsFrame{}, ws.fail(CloseProtocolError, "invalid payload length")
+		}
+		length = int64(raw)
+	}
+
+	if frame.opcode >= WebSocketClose && length > 125 {
+		return wsFrame{}, ws.fail(CloseProtocolError, "control frame payload too large")
+	}
+	if frame.opcode < WebSocketClose && ws.readLimit > 0 && length > ws.readLimit-buffered {
+		return wsFrame{}, ws.fail(CloseMessageTooBig, "message exceeds read limit")
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if _, err := io.ReadFull(ws.br, maskKey[:]); err != nil {
+			return wsFrame{}, ws.readError(err)
+		}
+	}
+
+	payload, err := readPayload(ws.br, length)
+	if err != nil {
+		return wsFrame{}, ws.readError(err)
+	}
+	frame.payload = payload
+	if masked {
+		maskBytes(maskKey, frame.payload)
+	}
+
+	return frame, nil
+}
+
+// wsPayloadChunk is the largest payload allocated up front. Longer ones are
+// read in steps, so memory grows with the bytes that actually arrive rather
+// than with the length a peer claims; that matters once SetReadLimit(0) has
+// disabled the limit and a header may announce up to 2^63-1 bytes.
+const wsPayloadChunk = 64 << 10
+
+func readPayload(r io.Reader, length int64) ([]byte, error) {
+	if length <= wsPayloadChunk {
+		payload := make([]byte, length)
+		_, err := io.ReadFull(r, payload)
+		return payload, err
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, r, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (ws *WebSocketConn) handleClose(payload []byte) error {
+	closeErr := &CloseError{Code: CloseNoStatusReceived}
+	reply := []byte{}
+
+	switch {
+	case len(payload) == 1:
+		return ws.fail(CloseProtocolError, "invalid close frame payload")
+	case len(payload) >= 2:
+		closeErr.Code = int(binary.BigEndian.Uint16(payload))
+		closeErr.Text = string(payload[2:])
+		if !validReceivedCloseCode(closeErr.Code) {
+			return ws.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return ws.fail(CloseInvalidPayload, "invalid UTF-8 in close reason")
+		}
+		reply = formatClosePayload(closeErr.Code, "")
+	}
+
+	_ = ws.WriteControl(WebSocketClose, reply)
+	ws.conn.Close()
+	return closeErr
+}
+
+func validReceivedCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003:
+		return true
+	case code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// fail sends a close frame for a protocol violation and tears down the connection.
+func (ws *WebSocketConn) fail(code int, reason string) error {
+	_ = ws.WriteControl(WebSocketClose, formatClosePayload(code, reason))
+	ws.conn.Close()
+	return &CloseError{Code: code, Text: reason}
+}
+
+func (ws *WebSocketConn) readError(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &CloseError{Code: CloseAbnormalClosure, Text: err.Error()}
+	}
+	return err
+}
+
+// ---------------------------
+// Writing
+// ---------------------------
+
+// WriteMessage sends data as a single unfragmented message.
+func (ws *WebSocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != WebSocketText && messageType != WebSocketBinary {
+		return ws.WriteControl(messageType, data)
+	}
+	ws.messageMu.Lock()
+	defer ws.messageMu.Unlock()
+	return ws.writeFrame(true, messageType, data)
+}
+
+func (ws *WebSocketConn) WriteText(text string) error {
+	return ws.WriteMessage(WebSocketText, []byte(text))
+}
+
+// NextWriter returns a writer that sends each Write as a separate fragment.
+// Closing the writer sends the final frame. Other data messages are blocked
+// until it is closed; control frames may still be interleaved.
+func (ws *WebSocketConn) NextWriter(messageType int) (io.WriteCloser, error) {
+	if messageType != WebSocketText && messageType != WebSocketBinary {
+		return nil, errors.New("kai: NextWriter requires a text or binary message type")
+	}
+	ws.messageMu.Lock()
+	return &wsMessageWriter{ws: ws, opcode: messageType}, nil
+}
+
+type wsMessageWriter struct {
+	ws      *WebSocketConn
+	opcode  int
+	started bool
+	closed  bool
+}
+
+func (w *wsMessageWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrWebSocketClosed
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	opcode := WebSocketContinuation
+	if !w.started {
+		opcode = w.opcode
+		w.started = true
+	}
+	if err := w.ws.writeFrame(false, opcode, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *wsMessageWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.ws.messageMu.Unlock()
+
+	opcode := WebSocketContinuation
+	if !w.started {
+		opcode = w.opcode
+	}
+	return w.ws.writeFrame(true, opcode, nil)
+}
+
+// WriteControl sends a close, ping or pong frame.
+func (ws *WebSocketConn) WriteControl(messageType int, data []byte) error {
+	if messageType != WebSocketClose && messageType != WebSocketPing && messageType != WebSocketPong {
+		return errors.New("kai: invalid control message type")
+	}
+	if len(data) > 125 {
+		return errors.New("kai: control frame payload exceeds 125 bytes")
+	}
+	return ws.writeFrame(true, messageType, data)
+}
+
+func (ws *WebSocketConn) Ping(data []byte) error {
+	return ws.WriteControl(WebSocketPing, data)
+}
+
+func (ws *WebSocketConn) writeFrame(fin bool, opcode int, payload []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	if ws.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == WebSocketClose {
+		ws.closeSent = true
+	}
+
+	header := make([]byte, 0, 14)
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	header = append(header, b0)
+
+	var maskBit byte
+	if !ws.isServer {
+		maskBit = 0x80
+	}
+
+	length := len(payload)
+	switch {
+	case length <= 125:
+		header = append(header, maskBit|byte(length))
+	case length <= 0xffff:
+		header = append(header, maskBit|126)
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header = append(header, maskBit|127)
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	frame := payload
+	if !ws.isServer {
+		var maskKey [4]byte
+		if _, err := rand.Read(maskKey[:]); err != nil {
+			return err
+		}
+		header = append(header, maskKey[:]...)
+		frame = make([]byte, length)
+		copy(frame, payload)
+		maskBytes(maskKey, frame)
+	}
+
+	if _, err := ws.conn.Write(append(header, frame...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+// formatClosePayload fits the reason into the 125-byte control frame limit,
+// cutting at a character boundary: a split UTF-8 sequence would make the
+// peer fail the connection with 1007 instead of reading the close.
+func formatClosePayload(code int, reason string) []byte {
+	if len(reason) > 123 {
+		cut := 123
+		for cut > 0 && !utf8.RuneStart(reason[cut]) {
+			cut--
+		}
+		reason = reason[:cut]
+	}
+	buf := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	return append(buf, reason...)
+}
+
+// ---------------------------
+// Closing
+// ---------------------------
+
+// Close sends a normal closure frame (if none was sent yet) and closes the connection.
+func (ws *WebSocketConn) Close() error {
+	return ws.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode sends a close frame with the given code and reason, then
+// closes the underlying connection.
+func (ws *WebSocketConn) CloseWithCode(code int, reason string) error {
+	err := ws.WriteControl(WebSocketClose, formatClosePayload(code, reason))
+	if errors.Is(err, ErrCloseSent) {
+		err = nil
+	}
+	if cerr := ws.conn.Close(); cerr != nil && !errors.Is(cerr, net.ErrClosed) && err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+package kai_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dipto-kainin/kai"
+	"github.com/dipto-kainin/kai/kaitest"
+)
+
+func newWebSocketServer(t *testing.T) *httptest.Server {
+	app := kai.NewApp()
+	app.GET("/echo", kai.WebSocket(func(c *kai.Context, ws *kai.WebSocketConn) {
+		for {
+			kind, data, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := ws.WriteMessage(kind, data); err != nil {
+				return
+			}
+		}
+	}, kai.WebSocketOptions{Subprotocols: []string{"chat.v2", "chat.v1"}, ReadLimit: 1024}))
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dialEcho(t *testing.T, srv *httptest.Server, header http.Header) (*kai.WebSocketConn, *http.Response) {
+	t.Helper()
+	return dialWebSocket(t, srv, "/echo", header)
+}
+
+func dialWebSocket(t *testing.T, srv *httptest.Server, path string, header http.Header) (*kai.WebSocketConn, *http.Response) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ws, resp, err := kai.DialWebSocket(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+path, header)
+	if err != nil {
+		t.Fatalf("DialWebSocket: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return ws, resp
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	srv := newWebSocketServer(t)
+	ws, resp := dialEcho(t, srv, http.Header{"Sec-WebSocket-Protocol": {"chat.v1, chat.v2"}})
+
+	if resp.StatusCode != http.StatusSwitchingProtocols || ws.Subprotocol() != "chat.v1" {
+		t.Fatalf("status = %d, subprotocol = %q", resp.StatusCode, ws.Subprotocol())
+	}
+
+	if err := ws.WriteText("hello"); err != nil {
+		t.Fatal(err)
+	}
+	kind, data, err := ws.ReadMessage()
+	if err != nil || kind != kai.WebSocketText || string(data) != "hello" {
+		t.Fatalf("ReadMessage = %d, %q, %v", kind, data, err)
+	}
+
+	// A fragmented binary message is reassembled by the server.
+	w, err := ws.NextWriter(kai.WebSocketBinary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte{1, 2})
+	w.Write([]byte{3})
+	w.Close()
+	kind, data, err = ws.ReadMessage()
+	if err != nil || kind != kai.WebSocketBinary || string(data) != "\x01\x02\x03" {
+		t.Fatalf("ReadMessage = %d, %v, %v", kind, data, err)
+	}
+
+	pong := make(chan string, 1)
+	ws.SetPongHandler(func(data []byte) error {
+		pong <- string(data)
+		return nil
+	})
+	if err := ws.Ping([]byte("p")); err != nil {
+		t.Fatal(err)
+	}
+	ws.WriteText("after ping")
+	if _, data, err := ws.ReadMessage(); err != nil || string(data) != "after ping" {
+		t.Fatalf("ReadMessage = %q, %v", data, err)
+	}
+	select {
+	case got := <-pong:
+		if got != "p" {
+			t.Errorf("pong payload = %q", got)
+		}
+	default:
+		t.Error("no pong received")
+	}
+}
+
+func TestWebSocketReadLimit(t *testing.T) {
+	srv := newWebSocketServer(t)
+	ws, _ := dialEcho(t, srv, nil)
+
+	ws.WriteMessage(kai.WebSocketBinary, make([]byte, 2048))
+	_, _, err := ws.ReadMessage()
+	var closeErr *kai.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != kai.CloseMessageTooBig {
+		t.Fatalf("ReadMessage error = %v, want close %d", err, kai.CloseMessageTooBig)
+	}
+}
+
+func TestWebSocketUnlimitedReadBoundsAllocation(t *testing.T) {
+	result := make(chan error, 1)
+	app := kai.NewApp()
+	app.GET("/ws", kai.WebSocket(func(c *kai.Context, ws *kai.WebSocketConn) {
+		ws.SetReadLimit(0)
+		_, data, err := ws.ReadMessage()
+		if err == nil {
+			// A large message that really arrives is still read in full.
+			ws.WriteMessage(kai.WebSocketBinary, data)
+			_, _, err = ws.ReadMessage()
+		}
+		result <- err
+	}))
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+
+	ws, _ := dialWebSocket(t, srv, "/ws", nil)
+	big := bytes.Repeat([]byte{7}, 200<<10)
+	ws.WriteMessage(kai.WebSocketBinary, big)
+	if _, data, err := ws.ReadMessage(); err != nil || !bytes.Equal(data, big) {
+		t.Fatalf("echo of %d bytes = %d bytes, %v", len(big), len(data), err)
+	}
+	ws.Close()
+	<-result
+
+	// A raw client announces a 2^62-byte frame and hangs up after a few bytes.
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(conn, "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake = %v, %v", resp, err)
+	}
+	frame := []byte{0x82, 0xff}
+	frame = binary.BigEndian.AppendUint64(frame, 1<<62)
+	frame = append(frame, 0, 0, 0, 0, 'x', 'y', 'z')
+	conn.Write(frame)
+	conn.Close()
+
+	select {
+	case err := <-result:
+		var closeErr *kai.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != kai.CloseAbnormalClosure {
+			t.Fatalf("ReadMessage error = %v, want abnormal closure", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler did not return; the oversized frame was not handled")
+	}
+}
+
+func TestWebSocketLongCloseReason(t *testing.T) {
+	reason := strings.Repeat("é", 100) // 200 bytes; byte 123 falls inside a character
+	app := kai.NewApp()
+	app.GET("/ws", kai.WebSocket(func(c *kai.Context, ws *kai.WebSocketConn) {
+		ws.CloseWithCode(kai.CloseGoingAway, reason)
+	}))
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+
+	ws, _ := dialWebSocket(t, srv, "/ws", nil)
+	_, _, err := ws.ReadMessage()
+	var closeErr *kai.CloseError
+	if !errors.As(err, &closeErr) || closeErr.Code != kai.CloseGoingAway {
+		t.Fatalf("ReadMessage error = %v, want close %d", err, kai.CloseGoingAway)
+	}
+	if want := strings.Repeat("é", 61); closeErr.Text != want {
+		t.Errorf("close reason = %q (%d bytes), want %d bytes of whole characters", closeErr.Text, len(closeErr.Text), len(want))
+	}
+}
+
+func TestWebSocketHandshakeErrors(t *testing.T) {
+	app := kai.NewApp()
+	app.GET("/ws", kai.WebSocket(func(c *kai.Context, ws *kai.WebSocketConn) {}))
+	client := kaitest.New(t, app)
+	upgrade := func() *kaitest.Request {
+		return client.GET("/ws").
+			Header("Connection", "Upgrade").
+			Header("Upgrade", "websocket").
+			Header("Sec-WebSocket-Version", "13").
+			Header("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	}
+
+	client.GET("/ws").Do().ExpectStatus(http.StatusBadRequest)
+	upgrade().Header("Sec-WebSocket-Version", "8").Do().
+		ExpectStatus(http.StatusUpgradeRequired).
+		ExpectHeader("Sec-WebSocket-Version", "13")
+	upgrade().Header("Sec-WebSocket-Key", "short").Do().ExpectStatus(http.StatusBadRequest)
+	upgrade().Header("Origin", "https://evil.example").Do().ExpectStatus(http.StatusForbidden)
+	// httptest.ResponseRecorder cannot be hijacked, which the handshake reports.
+	upgrade().Do().ExpectStatus(http.StatusInternalServerError)
+}